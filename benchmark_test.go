@@ -0,0 +1,399 @@
+package nilmapper
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// The benchmarks map the same shapes with Copy, with a hand-written function,
+// and with Copy of a source type implementing ToMapper with the same
+// hand-written code, which is what generated mapping code plugs into. The
+// difference between the last two is the cost of Copy itself.
+
+type benchFlat struct {
+	ID       int
+	Name     string
+	Email    string
+	Age      int
+	Score    float64
+	Active   bool
+	Country  string
+	Language string
+}
+
+type benchFlatDTO struct {
+	ID       int
+	Name     string
+	Email    string
+	Age      int
+	Score    float64
+	Active   bool
+	Country  string
+	Language string
+}
+
+type benchAddress struct {
+	Street string
+	City   string
+	Zip    string
+}
+
+type benchContact struct {
+	Email   string
+	Phone   string
+	Address benchAddress
+}
+
+type benchNested struct {
+	ID      int
+	Name    string
+	Contact benchContact
+	Billing *benchAddress
+}
+
+type benchAddressDTO struct {
+	Street string
+	City   string
+	Zip    string
+}
+
+type benchContactDTO struct {
+	Email   string
+	Phone   string
+	Address benchAddressDTO
+}
+
+type benchNestedDTO struct {
+	ID      int
+	Name    string
+	Contact benchContactDTO
+	Billing *benchAddressDTO
+}
+
+type benchPointers struct {
+	ID       *int
+	Name     *string
+	Email    *string
+	Age      *int
+	Score    *float64
+	Active   *bool
+	Country  string
+	Language string
+}
+
+type benchPointersDTO struct {
+	ID       int
+	Name     string
+	Email    *string
+	Age      int
+	Score    *float64
+	Active   bool
+	Country  *string
+	Language *string
+}
+
+type benchSlice struct {
+	ID    int
+	Items []benchFlat
+}
+
+type benchSliceDTO struct {
+	ID    int
+	Items []benchFlatDTO
+}
+
+type benchMaps struct {
+	ID     int
+	Labels map[string]string
+	Counts map[string]int
+}
+
+type benchMapsDTO struct {
+	ID     int
+	Labels map[string]string
+	Counts map[string]int
+}
+
+// benchSizes are the numbers of slice elements and map entries.
+var benchSizes = []int{1, 10, 100, 1000}
+
+func newBenchFlat(i int) benchFlat {
+	return benchFlat{
+		ID:       i,
+		Name:     "Ada Lovelace",
+		Email:    "ada@example.com",
+		Age:      36,
+		Score:    9.5,
+		Active:   true,
+		Country:  "UK",
+		Language: "en",
+	}
+}
+
+func newBenchNested() benchNested {
+	return benchNested{
+		ID:   1,
+		Name: "Ada",
+		Contact: benchContact{
+			Email:   "ada@example.com",
+			Phone:   "555-0100",
+			Address: benchAddress{Street: "Main", City: "London", Zip: "N1"},
+		},
+		Billing: &benchAddress{Street: "Side", City: "London", Zip: "N2"},
+	}
+}
+
+func newBenchPointers() benchPointers {
+	return benchPointers{
+		ID:       ToValue(1),
+		Name:     ToValue("Ada"),
+		Email:    ToValue("ada@example.com"),
+		Age:      ToValue(36),
+		Score:    ToValue(9.5),
+		Active:   ToValue(true),
+		Country:  "UK",
+		Language: "en",
+	}
+}
+
+func newBenchSlice(n int) benchSlice {
+	src := benchSlice{ID: 1, Items: make([]benchFlat, n)}
+	for i := range src.Items {
+		src.Items[i] = newBenchFlat(i)
+	}
+	return src
+}
+
+func newBenchMaps(n int) benchMaps {
+	src := benchMaps{ID: 1, Labels: make(map[string]string, n), Counts: make(map[string]int, n)}
+	for i := 0; i < n; i++ {
+		key := strconv.Itoa(i)
+		src.Labels[key] = "label " + key
+		src.Counts[key] = i
+	}
+	return src
+}
+
+func handFlat(src benchFlat) benchFlatDTO {
+	return benchFlatDTO{
+		ID:       src.ID,
+		Name:     src.Name,
+		Email:    src.Email,
+		Age:      src.Age,
+		Score:    src.Score,
+		Active:   src.Active,
+		Country:  src.Country,
+		Language: src.Language,
+	}
+}
+
+func handNested(src benchNested) benchNestedDTO {
+	dst := benchNestedDTO{
+		ID:   src.ID,
+		Name: src.Name,
+		Contact: benchContactDTO{
+			Email:   src.Contact.Email,
+			Phone:   src.Contact.Phone,
+			Address: benchAddressDTO(src.Contact.Address),
+		},
+	}
+	if src.Billing != nil {
+		billing := benchAddressDTO(*src.Billing)
+		dst.Billing = &billing
+	}
+	return dst
+}
+
+func handPointers(src benchPointers) benchPointersDTO {
+	var dst benchPointersDTO
+	if src.ID != nil {
+		dst.ID = *src.ID
+	}
+	if src.Name != nil {
+		dst.Name = *src.Name
+	}
+	if src.Email != nil {
+		email := *src.Email
+		dst.Email = &email
+	}
+	if src.Age != nil {
+		dst.Age = *src.Age
+	}
+	if src.Score != nil {
+		score := *src.Score
+		dst.Score = &score
+	}
+	if src.Active != nil {
+		dst.Active = *src.Active
+	}
+	country, language := src.Country, src.Language
+	dst.Country, dst.Language = &country, &language
+	return dst
+}
+
+func handSlice(src benchSlice) benchSliceDTO {
+	dst := benchSliceDTO{ID: src.ID, Items: make([]benchFlatDTO, len(src.Items))}
+	for i, item := range src.Items {
+		dst.Items[i] = handFlat(item)
+	}
+	return dst
+}
+
+// handMaps shares the maps of src, like Copy does.
+func handMaps(src benchMaps) benchMapsDTO {
+	return benchMapsDTO{ID: src.ID, Labels: src.Labels, Counts: src.Counts}
+}
+
+// benchSelfFlat maps itself into benchFlatDTO with handFlat.
+type benchSelfFlat benchFlat
+
+func (s benchSelfFlat) MapTo(dst any) (bool, error) {
+	d, ok := dst.(*benchFlatDTO)
+	if ok {
+		*d = handFlat(benchFlat(s))
+	}
+	return ok, nil
+}
+
+// benchCopy runs Copy of src into a new Dst b.N times.
+func benchCopy[Src, Dst any](b *testing.B, src Src) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var dst Dst
+		if err := Copy(src, &dst); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchHand runs the hand-written mapping of src b.N times.
+func benchHand[Src, Dst any](b *testing.B, src Src, hand func(Src) Dst) {
+	b.ReportAllocs()
+	var dst Dst
+	for i := 0; i < b.N; i++ {
+		dst = hand(src)
+	}
+	_ = dst
+}
+
+func BenchmarkFlat(b *testing.B) {
+	src := newBenchFlat(1)
+	b.Run("copy", func(b *testing.B) { benchCopy[benchFlat, benchFlatDTO](b, src) })
+	b.Run("self", func(b *testing.B) { benchCopy[benchSelfFlat, benchFlatDTO](b, benchSelfFlat(src)) })
+	b.Run("hand", func(b *testing.B) { benchHand(b, src, handFlat) })
+}
+
+func BenchmarkNested(b *testing.B) {
+	src := newBenchNested()
+	b.Run("copy", func(b *testing.B) { benchCopy[benchNested, benchNestedDTO](b, src) })
+	b.Run("hand", func(b *testing.B) { benchHand(b, src, handNested) })
+}
+
+func BenchmarkPointers(b *testing.B) {
+	src := newBenchPointers()
+	b.Run("copy", func(b *testing.B) { benchCopy[benchPointers, benchPointersDTO](b, src) })
+	b.Run("hand", func(b *testing.B) { benchHand(b, src, handPointers) })
+}
+
+func BenchmarkSliceOfStructs(b *testing.B) {
+	for _, n := range benchSizes {
+		src := newBenchSlice(n)
+		b.Run(fmt.Sprintf("copy/%d", n), func(b *testing.B) { benchCopy[benchSlice, benchSliceDTO](b, src) })
+		b.Run(fmt.Sprintf("hand/%d", n), func(b *testing.B) { benchHand(b, src, handSlice) })
+	}
+}
+
+func BenchmarkCopySlice(b *testing.B) {
+	for _, n := range benchSizes {
+		src := newBenchSlice(n).Items
+		b.Run(fmt.Sprintf("copy/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var dst []benchFlatDTO
+				if err := CopySlice(src, &dst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("parallel/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var dst []benchFlatDTO
+				if err := CopySlice(src, &dst, Parallelism(4)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("hand/%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			var dst []benchFlatDTO
+			for i := 0; i < b.N; i++ {
+				dst = make([]benchFlatDTO, len(src))
+				for j, item := range src {
+					dst[j] = handFlat(item)
+				}
+			}
+			_ = dst
+		})
+	}
+}
+
+func BenchmarkMaps(b *testing.B) {
+	for _, n := range benchSizes {
+		src := newBenchMaps(n)
+		b.Run(fmt.Sprintf("copy/%d", n), func(b *testing.B) { benchCopy[benchMaps, benchMapsDTO](b, src) })
+		b.Run(fmt.Sprintf("hand/%d", n), func(b *testing.B) { benchHand(b, src, handMaps) })
+	}
+}
+
+// allocBudgets are the most allocations Copy may make for each shape. Flat
+// structs and structs of maps have identical layouts, which must not
+// allocate. The other budgets leave about a fifth of headroom over the
+// measured counts, so that other Go versions do not fail the tests; lower
+// them when the mapper allocates less, so that regressions do.
+var allocBudgets = []struct {
+	name   string
+	budget float64
+	copy   func() error
+}{
+	{"flat", 0, copyInto[benchFlatDTO](newBenchFlat(1))},
+	{"nested", 60, copyInto[benchNestedDTO](newBenchNested())},
+	{"pointers", 52, copyInto[benchPointersDTO](newBenchPointers())},
+	{"slice of structs", 52, copyInto[benchSliceDTO](newBenchSlice(10))},
+	{"maps", 0, copyInto[benchMapsDTO](newBenchMaps(10))},
+}
+
+// copyInto returns a function copying src into the same zeroed Dst, so that
+// only the allocations of Copy are counted.
+func copyInto[Dst any](src interface{}) func() error {
+	dst := new(Dst)
+	return func() error {
+		var zero Dst
+		*dst = zero
+		return Copy(src, dst)
+	}
+}
+
+func TestAllocationBudget(t *testing.T) {
+	for _, tt := range allocBudgets {
+		var err error
+		allocs := testing.AllocsPerRun(100, func() {
+			if e := tt.copy(); e != nil {
+				err = e
+			}
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		budget := tt.budget
+		if raceEnabled {
+			// sync.Pool drops items at random under the race detector, so
+			// the runs of Copy are allocated again.
+			budget += budget/4 + 1
+		}
+		if allocs > budget {
+			t.Errorf("%s: %v allocations, want at most %v", tt.name, allocs, budget)
+		}
+	}
+}
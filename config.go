@@ -0,0 +1,442 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// typePair identifies the source and destination struct types of a mapping.
+type typePair struct {
+	src reflect.Type
+	dst reflect.Type
+}
+
+func (m *Mapper) lookupTypeMap(src reflect.Type, dst reflect.Type) *typeMap {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.maps[typePair{src: src, dst: dst}]
+}
+
+// Config is the explicit mapping configuration of a Src to Dst type pair.
+// It is created with Configure and applied by Copy and CopySlice whenever a
+// Src value is mapped into a Dst value, at the top level or as a nested field.
+type Config[Src, Dst any] struct {
+	m  *Mapper
+	tm *typeMap
+}
+
+// Configure registers an explicit mapping configuration for the Src to Dst
+// type pair and returns it for further setup. Calling Configure again for the
+// same pair replaces the previous configuration.
+//
+// Paths are dot separated field names and may start with the name of the
+// struct type they belong to. Every path is validated when it is added, and
+// the problems found are reported by Err and by every Copy of the pair:
+//
+//	cfg := nilmapper.Configure[Src, Dst]().
+//		Field("Dst.Owner.Email", "Src.User.Contact.Email").
+//		Ignore("Dst.Internal")
+//	if err := cfg.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+//
+// The configuration must be complete before it is used concurrently by Copy.
+func Configure[Src, Dst any]() *Config[Src, Dst] {
+	return ConfigureOn[Src, Dst](defaultMapper)
+}
+
+// ConfigureOn is like Configure, but registers the configuration on m
+// instead of the default Mapper.
+func ConfigureOn[Src, Dst any](m *Mapper) *Config[Src, Dst] {
+	tm := newTypeMap(typeOf[Src](), typeOf[Dst](), m.opts.matcher)
+
+	m.mu.Lock()
+	m.maps[typePair{src: tm.src, dst: tm.dst}] = tm
+	m.mu.Unlock()
+	return &Config[Src, Dst]{m: m, tm: tm}
+}
+
+// Field maps the source field at srcPath into the destination field at
+// dstPath. If a pointer on the source path is nil the destination field is
+// left untouched, while nil pointers on the destination path are allocated.
+func (c *Config[Src, Dst]) Field(dstPath string, srcPath string) *Config[Src, Dst] {
+	c.tm.field(dstPath, srcPath)
+	return c
+}
+
+// ForField computes the destination field at dstPath with a resolver
+// function instead of copying a source field. The resolver receives the
+// source struct, either as Src or as *Src, optionally preceded by the
+// context.Context given to CopyContext, and returns the value of the field,
+// optionally followed by an error:
+//
+//	Configure[User, UserDTO]().
+//		ForField("FullName", func(src User) string {
+//			return src.First + " " + src.Last
+//		})
+//
+// Resolvers run after the fields have been mapped by name, and an error
+// returned by a resolver is returned by Copy.
+func (c *Config[Src, Dst]) ForField(dstPath string, resolver any) *Config[Src, Dst] {
+	c.tm.resolve(dstPath, reflect.ValueOf(resolver))
+	return c
+}
+
+// Ignore excludes the destination field at dstPath from the mapping.
+func (c *Config[Src, Dst]) Ignore(dstPath string) *Config[Src, Dst] {
+	c.tm.ignore(dstPath)
+	return c
+}
+
+// Err returns the problems found while setting up the configuration, or nil.
+func (c *Config[Src, Dst]) Err() error {
+	return c.tm.err
+}
+
+// typeMap is the untyped form of a Config.
+type typeMap struct {
+	src    reflect.Type
+	dst    reflect.Type
+	rules  []fieldRule
+	before []hookFunc
+	after  []hookFunc
+	// skip holds the destination paths which are not mapped by name, because
+	// they are either ignored or assigned by a rule.
+	skip *pathNode
+	// used holds the source fields read by the rules.
+	used map[string]bool
+	// merges holds the slice fields merged by key.
+	merges map[string]mergeSpec
+	// ignored holds the destination paths ignored with Ignore.
+	ignored []fieldPath
+	// inverses holds the inverses of the resolvers, by destination path.
+	inverses map[string]inverseRule
+	// filled holds the destination paths filled by the after hooks of a
+	// reverse configuration.
+	filled []filledPath
+	// irreversible holds the source paths which a reverse configuration
+	// cannot map back, because the resolver producing them has no inverse.
+	irreversible []string
+	// fields resolves the names of the paths.
+	fields fieldIndexes
+	err    error
+}
+
+// fieldRule assigns the destination path either from a source path or from
+// the result of a resolver function.
+type fieldRule struct {
+	dst      fieldPath
+	src      fieldPath
+	resolver reflect.Value
+}
+
+func newTypeMap(src reflect.Type, dst reflect.Type, matcher NameMatcher) *typeMap {
+	tm := &typeMap{src: indirectType(src), dst: indirectType(dst), skip: &pathNode{}, used: make(map[string]bool)}
+	tm.fields.matcher = matcher
+	if tm.src.Kind() != reflect.Struct || tm.dst.Kind() != reflect.Struct {
+		tm.fail(errors.New("both types must be structs"))
+	}
+	return tm
+}
+
+func (tm *typeMap) fail(err error) {
+	tm.err = errors.Join(tm.err, fmt.Errorf("nilmapper: Configure[%s, %s]: %w", tm.src, tm.dst, err))
+}
+
+func (tm *typeMap) claim(dst fieldPath, ignored bool) bool {
+	if !tm.skip.add(dst.names(), ignored) {
+		tm.fail(fmt.Errorf("%s is configured more than once", dst))
+		return false
+	}
+	return true
+}
+
+func (tm *typeMap) field(dstPath string, srcPath string) {
+	dst, err := tm.resolvePath(tm.dst, dstPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	src, err := tm.resolvePath(tm.src, srcPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	if !mappable(src.typ(), dst.typ()) {
+		tm.fail(fmt.Errorf("cannot map %s (%s) into %s (%s)", src, src.typ(), dst, dst.typ()))
+		return
+	}
+	if tm.claim(dst, false) {
+		tm.rules = append(tm.rules, fieldRule{dst: dst, src: src})
+		tm.used[src[0].Name] = true
+	}
+}
+
+var (
+	errorType   = typeOf[error]()
+	contextType = typeOf[context.Context]()
+)
+
+func (tm *typeMap) resolve(dstPath string, resolver reflect.Value) {
+	dst, err := tm.resolvePath(tm.dst, dstPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	if err := checkResolver(resolver, tm.src, dst.typ()); err != nil {
+		tm.fail(fmt.Errorf("resolver for %s: %w", dst, err))
+		return
+	}
+	if tm.claim(dst, false) {
+		tm.rules = append(tm.rules, fieldRule{dst: dst, resolver: resolver})
+	}
+}
+
+// checkResolver reports whether resolver is a func(src) T or a
+// func(src) (T, error), where src is srcType or a pointer to it, optionally
+// preceded by a context.Context, and T can be mapped into destType.
+func checkResolver(resolver reflect.Value, srcType reflect.Type, destType reflect.Type) error {
+	if resolver.Kind() != reflect.Func || resolver.IsNil() {
+		return errors.New("resolver is not a function")
+	}
+	t := resolver.Type()
+	in := t.NumIn()
+	if in == 2 && t.In(0) == contextType {
+		in = 1
+	}
+	if in != 1 || (t.In(t.NumIn()-1) != srcType && t.In(t.NumIn()-1) != reflect.PtrTo(srcType)) {
+		return fmt.Errorf("%s must take a single %s or *%s argument, optionally preceded by a context.Context", t, srcType, srcType)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return fmt.Errorf("%s must return a value, optionally followed by an error", t)
+	}
+	if !mappable(t.Out(0), destType) {
+		return fmt.Errorf("cannot map %s into %s", t.Out(0), destType)
+	}
+	return nil
+}
+
+func (tm *typeMap) ignore(dstPath string) {
+	dst, err := tm.resolvePath(tm.dst, dstPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	if tm.claim(dst, true) {
+		tm.ignored = append(tm.ignored, dst)
+	}
+}
+
+// apply runs the configured rules once the fields have been mapped by name.
+func (tm *typeMap) apply(r *run, srcValue reflect.Value, destValue reflect.Value) error {
+	for _, rule := range tm.rules {
+		r.enter(rule.dst.String())
+		recording := r.recording()
+		r.quiet++
+		written, err := rule.run(r, srcValue, destValue)
+		r.quiet--
+		if recording && err == nil {
+			if written {
+				r.report.Written = append(r.report.Written, r.reportPath(""))
+			} else {
+				r.report.SkippedNil = append(r.report.SkippedNil, r.reportPath(""))
+			}
+		}
+		r.leave()
+		if err != nil {
+			return fmt.Errorf("%s: %w", rule.dst, err)
+		}
+	}
+	return nil
+}
+
+// run assigns the destination path of the rule. It reports false if the
+// source path holds a nil pointer, so nothing was assigned.
+func (rule fieldRule) run(r *run, srcValue reflect.Value, destValue reflect.Value) (bool, error) {
+	if rule.resolver.IsValid() {
+		return true, rule.resolve(r, srcValue, destValue)
+	}
+	srcFieldValue, ok := rule.src.get(srcValue)
+	if !ok || srcFieldValue.Kind() == reflect.Ptr && srcFieldValue.IsNil() {
+		return false, nil
+	}
+	return true, r.mapField(srcFieldValue, rule.dst.alloc(destValue), nil)
+}
+
+func (rule fieldRule) resolve(r *run, srcValue reflect.Value, destValue reflect.Value) error {
+	t := rule.resolver.Type()
+	arg := srcValue
+	if t.In(t.NumIn()-1).Kind() == reflect.Ptr {
+		arg = addressable(arg).Addr()
+	}
+	args := []reflect.Value{arg}
+	if t.NumIn() == 2 {
+		args = []reflect.Value{reflect.ValueOf(&r.ctx).Elem(), arg}
+	}
+	out := rule.resolver.Call(args)
+	if len(out) == 2 && !out[1].IsNil() {
+		return out[1].Interface().(error)
+	}
+	return r.mapField(out[0], rule.dst.alloc(destValue), nil)
+}
+
+// fieldPath is a validated chain of fields starting at a struct type.
+type fieldPath []reflect.StructField
+
+func (tm *typeMap) resolvePath(root reflect.Type, path string) (fieldPath, error) {
+	segments := strings.Split(path, ".")
+	if len(segments) > 1 && segments[0] == root.Name() {
+		if _, ok := root.FieldByName(segments[0]); !ok {
+			segments = segments[1:]
+		}
+	}
+
+	var fields fieldPath
+	t := root
+	for _, segment := range segments {
+		t = indirectType(t)
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("path %q: %s is not a struct", path, t)
+		}
+		field, ok, err := tm.fields.lookup(t, segment)
+		if err != nil {
+			return nil, fmt.Errorf("path %q: %w", path, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("path %q: no field %q in %s", path, segment, t)
+		}
+		if !field.IsExported() {
+			return nil, fmt.Errorf("path %q: field %q of %s is not exported", path, segment, t)
+		}
+		fields = append(fields, field)
+		t = field.Type
+	}
+	return fields, nil
+}
+
+func (p fieldPath) names() []string {
+	names := make([]string, len(p))
+	for i, field := range p {
+		names[i] = field.Name
+	}
+	return names
+}
+
+func (p fieldPath) String() string {
+	return strings.Join(p.names(), ".")
+}
+
+func (p fieldPath) typ() reflect.Type {
+	return p[len(p)-1].Type
+}
+
+// get walks the path from v and reports false if it runs into a nil pointer.
+func (p fieldPath) get(v reflect.Value) (reflect.Value, bool) {
+	for _, field := range p {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		var err error
+		v, err = v.FieldByIndexErr(field.Index)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// alloc walks the path from v, allocating nil pointers on the way, and
+// returns the settable field at its end.
+func (p fieldPath) alloc(v reflect.Value) reflect.Value {
+	for _, field := range p {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = fieldByIndexAlloc(v, field.Index)
+	}
+	return v
+}
+
+// pathNode is a set of field paths stored as a tree of field names. Each
+// path is either ignored or assigned by a rule.
+type pathNode struct {
+	leaf     bool
+	ignored  bool
+	children map[string]*pathNode
+}
+
+// add inserts names into the set and reports false if the path, one of its
+// parents or one of its children is already in it.
+func (n *pathNode) add(names []string, ignored bool) bool {
+	for _, name := range names {
+		if n.leaf {
+			return false
+		}
+		if n.children == nil {
+			n.children = make(map[string]*pathNode)
+		}
+		child, ok := n.children[name]
+		if !ok {
+			child = &pathNode{}
+			n.children[name] = child
+		}
+		n = child
+	}
+	if n.leaf || len(n.children) > 0 {
+		return false
+	}
+	n.leaf = true
+	n.ignored = ignored
+	return true
+}
+
+// skipSet is the union of the path sets that apply to a struct being mapped.
+type skipSet []*pathNode
+
+func (s skipSet) has(name string) bool {
+	for _, n := range s {
+		if child := n.children[name]; child != nil && child.leaf {
+			return true
+		}
+	}
+	return false
+}
+
+// ignores reports whether the path name is ignored.
+func (s skipSet) ignores(name string) bool {
+	for _, n := range s {
+		if child := n.children[name]; child != nil && child.leaf && child.ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// covers reports whether the path name, or one below it, is in the set.
+func (s skipSet) covers(name string) bool {
+	for _, n := range s {
+		if n.children[name] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s skipSet) child(name string) skipSet {
+	var children skipSet
+	for _, n := range s {
+		if child := n.children[name]; child != nil {
+			children = append(children, child)
+		}
+	}
+	return children
+}
@@ -0,0 +1,190 @@
+package nilmapper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type Contact struct {
+	Email *string
+}
+
+type Account struct {
+	Contact *Contact
+}
+
+type OrderSrc struct {
+	Title    string
+	User     Account
+	Internal string
+}
+
+type Owner struct {
+	Name  string
+	Email string
+}
+
+type OrderDst struct {
+	Title    string
+	Owner    *Owner
+	Internal string
+}
+
+func TestConfigure(t *testing.T) {
+	cfg := Configure[OrderSrc, OrderDst]().
+		Field("OrderDst.Owner.Email", "OrderSrc.User.Contact.Email").
+		Ignore("OrderDst.Internal")
+	assert.Equal(t, cfg.Err(), nil)
+
+	t.Run("Path", func(t *testing.T) {
+		src := OrderSrc{
+			Title:    "Order",
+			User:     Account{Contact: &Contact{Email: ToValue("owner@example.com")}},
+			Internal: "secret",
+		}
+		var dest OrderDst
+		assert.Equal(t, Copy(src, &dest), nil)
+		assert.Equal(t, dest.Title, "Order")
+		assert.Equal(t, dest.Owner.Email, "owner@example.com")
+		assert.Equal(t, dest.Internal, "")
+	})
+
+	t.Run("Nil Path", func(t *testing.T) {
+		src := OrderSrc{Title: "Order", User: Account{Contact: nil}}
+		dest := OrderDst{Owner: &Owner{Email: "kept@example.com"}}
+		assert.Equal(t, Copy(src, &dest), nil)
+		assert.Equal(t, dest.Owner.Email, "kept@example.com")
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		src := []struct{ Order OrderSrc }{{
+			Order: OrderSrc{User: Account{Contact: &Contact{Email: ToValue("nested@example.com")}}, Internal: "secret"},
+		}}
+		var dest []struct{ Order OrderDst }
+		assert.Equal(t, CopySlice(src, &dest), nil)
+		assert.Equal(t, dest[0].Order.Owner.Email, "nested@example.com")
+		assert.Equal(t, dest[0].Order.Internal, "")
+	})
+}
+
+type IgnoreSrc struct {
+	Owner Owner
+}
+
+type IgnoreDst struct {
+	Owner Owner
+}
+
+func TestConfigureIgnoreNested(t *testing.T) {
+	assert.Equal(t, Configure[IgnoreSrc, IgnoreDst]().Ignore("Owner.Email").Err(), nil)
+
+	var dest IgnoreDst
+	assert.Equal(t, Copy(IgnoreSrc{Owner: Owner{Name: "Name", Email: "Email"}}, &dest), nil)
+	assert.Equal(t, dest.Owner, Owner{Name: "Name"})
+}
+
+type InvalidSrc struct {
+	Name  string
+	Count int
+}
+
+type InvalidDst struct {
+	Name  string
+	Count string
+}
+
+func TestConfigureInvalid(t *testing.T) {
+	cfg := Configure[InvalidSrc, InvalidDst]().
+		Field("Missing", "Name").
+		Field("Name", "Name.Value").
+		Field("Count", "Count").
+		Ignore("Name").
+		Ignore("Name")
+
+	err := cfg.Err()
+	if err == nil {
+		t.Fatal("expected a configuration error")
+	}
+	for _, want := range []string{`no field "Missing"`, "is not a struct", "cannot map Count", "Name is configured more than once"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+
+	var dest InvalidDst
+	assert.Equal(t, Copy(InvalidSrc{Name: "Name"}, &dest), err)
+	assert.Equal(t, dest.Name, "")
+}
+
+type Item struct {
+	Price float64
+}
+
+type Customer struct {
+	First string
+	Last  string
+	Items []Item
+}
+
+type CustomerDTO struct {
+	First    string
+	FullName string
+	Total    *float64
+}
+
+func TestConfigureForField(t *testing.T) {
+	cfg := Configure[Customer, CustomerDTO]().
+		ForField("FullName", func(src Customer) string {
+			return src.First + " " + src.Last
+		}).
+		ForField("Total", func(src *Customer) (float64, error) {
+			var total float64
+			for _, item := range src.Items {
+				if item.Price < 0 {
+					return 0, errors.New("negative price")
+				}
+				total += item.Price
+			}
+			return total, nil
+		})
+	assert.Equal(t, cfg.Err(), nil)
+
+	var dest CustomerDTO
+	assert.Equal(t, Copy(Customer{First: "Ada", Last: "Lovelace", Items: []Item{{Price: 1.5}, {Price: 2}}}, &dest), nil)
+	assert.Equal(t, dest.First, "Ada")
+	assert.Equal(t, dest.FullName, "Ada Lovelace")
+	assert.Equal(t, *dest.Total, 3.5)
+
+	var dests []CustomerDTO
+	err := CopySlice([]Customer{{Items: []Item{{Price: -1}}}}, &dests)
+	if err == nil || err.Error() != "nilmapper: index 0: Total: negative price" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+type CustomerView struct {
+	First    string
+	FullName string
+	Total    float64
+}
+
+func TestConfigureForFieldInvalid(t *testing.T) {
+	cfg := Configure[Customer, CustomerView]().
+		ForField("FullName", "First").
+		ForField("First", func(src CustomerView) string { return "" }).
+		ForField("Total", func(src Customer) (float64, string) { return 0, "" }).
+		ForField("Missing", func(src Customer) string { return "" })
+
+	err := cfg.Err()
+	if err == nil {
+		t.Fatal("expected a configuration error")
+	}
+	for _, want := range []string{"resolver is not a function", "must take a single", "must return a value", `no field "Missing"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
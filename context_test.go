@@ -0,0 +1,65 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type tenantKey struct{}
+
+type Row struct {
+	ID    int
+	Price float64
+}
+
+type RowDTO struct {
+	ID     int
+	Tenant string
+}
+
+func TestCopyContext(t *testing.T) {
+	m := New()
+	var resolved []int
+	cancelAt := -1
+	var cancel context.CancelFunc
+	cfg := ConfigureOn[Row, RowDTO](m).
+		ForField("Tenant", func(ctx context.Context, src Row) (string, error) {
+			resolved = append(resolved, src.ID)
+			if src.ID == cancelAt {
+				cancel()
+			}
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant, nil
+		})
+	assert.Equal(t, cfg.Err(), nil)
+
+	t.Run("Values", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+		var dest RowDTO
+		assert.Equal(t, m.CopyContext(ctx, Row{ID: 1}, &dest), nil)
+		assert.Equal(t, dest, RowDTO{ID: 1, Tenant: "acme"})
+
+		// Without a context the resolver gets context.Background.
+		assert.Equal(t, m.Copy(Row{ID: 2}, &dest), nil)
+		assert.Equal(t, dest, RowDTO{ID: 2})
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		defer cancel()
+		resolved, cancelAt = nil, 2
+
+		var dest []RowDTO
+		err := m.CopySliceContext(ctx, []Row{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}}, &dest)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		assert.Equal(t, err.Error(), "nilmapper: index 3: context canceled")
+		assert.Equal(t, resolved, []int{0, 1, 2})
+		assert.Equal(t, dest, []RowDTO(nil))
+	})
+}
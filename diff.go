@@ -0,0 +1,257 @@
+package nilmapper
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ChangeKind tells how a destination value changes.
+type ChangeKind string
+
+const (
+	// ChangeSet is a value replaced by another non-zero value.
+	ChangeSet ChangeKind = "set"
+	// ChangeCleared is a value replaced by its zero value, or a pointer set
+	// to nil.
+	ChangeCleared ChangeKind = "cleared"
+	// ChangeAdded is a slice element or map entry which is added.
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved is a slice element or map entry which is removed.
+	ChangeRemoved ChangeKind = "removed"
+)
+
+// Change is a destination value which Copy would change. Path is made of
+// field names, slice indexes and map keys, for example Items[2].Quantity.
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+	Kind ChangeKind  `json:"kind"`
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s %v -> %v", c.Path, c.Kind, c.Old, c.New)
+}
+
+// Diff returns the changes Copy would make to destination, a pointer to a
+// struct, when mapping source into it with the same options, without
+// changing destination. It is meant for audit logs and to skip writes which
+// would change nothing:
+//
+//	changes, err := nilmapper.Diff(patch, &user)
+//	if err == nil && len(changes) == 0 {
+//		return nil
+//	}
+func Diff(source interface{}, destination interface{}, opts ...Option) ([]Change, error) {
+	return defaultMapper.Diff(source, destination, opts...)
+}
+
+// Diff is like the package level Diff, but uses the configurations, hooks
+// and options of m.
+func (m *Mapper) Diff(source interface{}, destination interface{}, opts ...Option) ([]Change, error) {
+	if err := checkDestination(destination); err != nil {
+		return nil, err
+	}
+	r := m.newRun(context.Background(), opts)
+	r.dryRun = true
+	next := reflect.ValueOf(r.target(destination))
+	if err := r.mapStruct(source, next.Interface()); err != nil {
+		return nil, err
+	}
+	if err := r.finish(); err != nil {
+		return nil, err
+	}
+	var d differ
+	d.values("", reflect.ValueOf(destination).Elem(), next.Elem())
+	return d.changes, nil
+}
+
+// deepCopy copies src into dest, which must both be addressable, without
+// sharing the pointers, slices or maps of exported fields, so that mapping
+// into dest leaves src untouched. Unexported fields are assigned as they
+// are, since their internals, such as mutex states or driver handles, must
+// not be duplicated. Values reached several times, including through cycles,
+// are copied once.
+func deepCopy(dest reflect.Value, src reflect.Value) {
+	c := cloner{copies: make(map[refKey]reflect.Value)}
+	c.copy(dest, src)
+}
+
+// refKey identifies the value a pointer, slice or map refers to.
+type refKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// cloner deep copies values, mapping the references it has already copied
+// to their copies.
+type cloner struct {
+	copies map[refKey]reflect.Value
+}
+
+func (c *cloner) copy(dest reflect.Value, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		key := refKey{ptr: src.Pointer(), typ: src.Type()}
+		if ptr, ok := c.copies[key]; ok {
+			dest.Set(ptr)
+			return
+		}
+		ptr := reflect.New(src.Type().Elem())
+		c.copies[key] = ptr
+		c.copy(ptr.Elem(), src.Elem())
+		dest.Set(ptr)
+	case reflect.Struct:
+		dest.Set(src)
+		t := src.Type()
+		for i := 0; i < src.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				c.copy(dest.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		key := refKey{ptr: src.Pointer(), typ: src.Type(), len: src.Len()}
+		if slice, ok := c.copies[key]; ok {
+			dest.Set(slice)
+			return
+		}
+		slice := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		c.copies[key] = slice
+		for i := 0; i < src.Len(); i++ {
+			c.copy(slice.Index(i), src.Index(i))
+		}
+		dest.Set(slice)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			c.copy(dest.Index(i), src.Index(i))
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		key := refKey{ptr: src.Pointer(), typ: src.Type()}
+		if m, ok := c.copies[key]; ok {
+			dest.Set(m)
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		c.copies[key] = m
+		iter := src.MapRange()
+		for iter.Next() {
+			value := reflect.New(src.Type().Elem()).Elem()
+			c.copy(value, addressable(iter.Value()))
+			m.SetMapIndex(iter.Key(), value)
+		}
+		dest.Set(m)
+	default:
+		dest.Set(src)
+	}
+}
+
+// differ collects the changes between two values of the same type. Unexported
+// fields are not compared.
+type differ struct {
+	changes []Change
+	// visited holds the pairs of pointers already compared, so that cycles
+	// are followed once.
+	visited map[[2]refKey]bool
+}
+
+func (d *differ) add(path string, kind ChangeKind, old reflect.Value, next reflect.Value) {
+	change := Change{Path: path, Kind: kind}
+	if old.IsValid() {
+		change.Old = old.Interface()
+	}
+	if next.IsValid() {
+		change.New = next.Interface()
+	}
+	d.changes = append(d.changes, change)
+}
+
+func (d *differ) values(path string, old reflect.Value, next reflect.Value) {
+	switch old.Kind() {
+	case reflect.Ptr:
+		switch {
+		case old.IsNil() && next.IsNil():
+		case old.IsNil():
+			d.add(path, ChangeSet, reflect.Value{}, next.Elem())
+		case next.IsNil():
+			d.add(path, ChangeCleared, old.Elem(), reflect.Value{})
+		default:
+			pair := [2]refKey{{ptr: old.Pointer(), typ: old.Type()}, {ptr: next.Pointer(), typ: next.Type()}}
+			if d.visited[pair] {
+				return
+			}
+			if d.visited == nil {
+				d.visited = make(map[[2]refKey]bool)
+			}
+			d.visited[pair] = true
+			d.values(path, old.Elem(), next.Elem())
+		}
+	case reflect.Struct:
+		t := old.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).IsExported() {
+				d.values(joinPath(path, t.Field(i).Name), old.Field(i), next.Field(i))
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		n := old.Len()
+		if next.Len() < n {
+			n = next.Len()
+		}
+		for i := 0; i < n; i++ {
+			d.values(fmt.Sprintf("%s[%d]", path, i), old.Index(i), next.Index(i))
+		}
+		for i := n; i < next.Len(); i++ {
+			d.add(fmt.Sprintf("%s[%d]", path, i), ChangeAdded, reflect.Value{}, next.Index(i))
+		}
+		for i := n; i < old.Len(); i++ {
+			d.add(fmt.Sprintf("%s[%d]", path, i), ChangeRemoved, old.Index(i), reflect.Value{})
+		}
+	case reflect.Map:
+		keys := old.MapKeys()
+		for _, key := range next.MapKeys() {
+			if !old.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			keyPath := fmt.Sprintf("%s[%v]", path, key.Interface())
+			oldValue, nextValue := old.MapIndex(key), next.MapIndex(key)
+			switch {
+			case !oldValue.IsValid():
+				d.add(keyPath, ChangeAdded, reflect.Value{}, nextValue)
+			case !nextValue.IsValid():
+				d.add(keyPath, ChangeRemoved, oldValue, reflect.Value{})
+			default:
+				d.values(keyPath, oldValue, nextValue)
+			}
+		}
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		if old.Pointer() != next.Pointer() {
+			d.add(path, ChangeSet, old, next)
+		}
+	default:
+		if reflect.DeepEqual(old.Interface(), next.Interface()) {
+			return
+		}
+		if next.IsZero() {
+			d.add(path, ChangeCleared, old, next)
+		} else {
+			d.add(path, ChangeSet, old, next)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package nilmapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type ProfilePatch struct {
+	Name    *string
+	Email   string
+	Address *StrictAddress
+	Tags    []string
+	Items   []PatchItem
+}
+
+type Profile struct {
+	Name    string
+	EMAIL   string
+	Address *StrictAddress
+	Tags    []string
+	Items   []StoredItem `nilmapper:",mergekey=ID"`
+	Labels  map[string]string
+}
+
+func TestDiff(t *testing.T) {
+	dest := Profile{
+		Name:    "Ada",
+		EMAIL:   "ada@example.com",
+		Address: &StrictAddress{Street: "Main", City: "Delft"},
+		Tags:    []string{"a", "b"},
+		Items:   []StoredItem{{ID: 1, Quantity: 1, Name: "one"}},
+		Labels:  map[string]string{"k": "v"},
+	}
+
+	t.Run("Changes", func(t *testing.T) {
+		patch := ProfilePatch{
+			Email:   "",
+			Address: &StrictAddress{Street: "Main"},
+			Tags:    []string{"a"},
+			Items:   []PatchItem{{ID: 1, Quantity: 3}, {ID: 2, Quantity: 1}},
+		}
+		changes, err := Diff(patch, &dest)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, changes, []Change{
+			{Path: "EMAIL", Old: "ada@example.com", New: "", Kind: ChangeCleared},
+			{Path: "Address.City", Old: "Delft", New: "", Kind: ChangeCleared},
+			{Path: "Tags[1]", Old: "b", Kind: ChangeRemoved},
+			{Path: "Items[0].Quantity", Old: 1, New: 3, Kind: ChangeSet},
+			{Path: "Items[1]", New: StoredItem{ID: 2, Quantity: 1}, Kind: ChangeAdded},
+		})
+
+		// The destination is left untouched.
+		assert.Equal(t, dest.EMAIL, "ada@example.com")
+		assert.Equal(t, dest.Address.City, "Delft")
+		assert.Equal(t, dest.Items, []StoredItem{{ID: 1, Quantity: 1, Name: "one"}})
+	})
+
+	t.Run("No op", func(t *testing.T) {
+		patch := ProfilePatch{
+			Email:   "ada@example.com",
+			Address: &StrictAddress{Street: "Main", City: "Delft"},
+			Tags:    []string{"a", "b"},
+		}
+		changes, err := Diff(patch, &dest)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(changes), 0)
+	})
+
+	t.Run("Pointers", func(t *testing.T) {
+		var dest struct{ Address *StrictAddress }
+		changes, err := Diff(struct{ Address StrictAddress }{Address: StrictAddress{City: "Delft"}}, &dest)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, changes, []Change{{Path: "Address", New: StrictAddress{City: "Delft"}, Kind: ChangeSet}})
+	})
+}
+
+type treeNode struct {
+	Name     string
+	Parent   *treeNode
+	Children []*treeNode
+}
+
+func TestDiffCycle(t *testing.T) {
+	root := &treeNode{Name: "root"}
+	child := &treeNode{Name: "a", Parent: root}
+	root.Children = []*treeNode{child}
+
+	changes, err := Diff(struct{ Name string }{Name: "x"}, child)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, changes, []Change{{Path: "Name", Old: "a", New: "x", Kind: ChangeSet}})
+	assert.Equal(t, child.Name, "a")
+	assert.Equal(t, root.Children[0] == child, true)
+}
+
+func TestDeepCopyUnexported(t *testing.T) {
+	type handle struct{ open bool }
+	type conn struct {
+		Name   *string
+		handle *handle
+	}
+	src := conn{Name: ToValue("db"), handle: &handle{open: true}}
+	var dest conn
+	deepCopy(reflect.ValueOf(&dest).Elem(), reflect.ValueOf(src))
+	assert.Equal(t, *dest.Name, "db")
+	assert.Equal(t, dest.Name == src.Name, false)
+	// Unexported internals are assigned, not duplicated.
+	assert.Equal(t, dest.handle == src.handle, true)
+}
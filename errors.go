@@ -0,0 +1,28 @@
+package nilmapper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnmatchedFieldsError is returned by Copy and CopySlice when
+// RequireAllDestinationFields or RequireAllSourceFields is set and some
+// fields were not matched. The fields are listed by their path from the
+// mapped value, for example "Owner.Email", once per path even if they are
+// part of a slice. The destination has been mapped as far as possible when
+// the error is returned.
+type UnmatchedFieldsError struct {
+	Destination []string
+	Source      []string
+}
+
+func (e *UnmatchedFieldsError) Error() string {
+	var parts []string
+	if len(e.Destination) > 0 {
+		parts = append(parts, fmt.Sprintf("unmatched destination fields: %s", strings.Join(e.Destination, ", ")))
+	}
+	if len(e.Source) > 0 {
+		parts = append(parts, fmt.Sprintf("unmatched source fields: %s", strings.Join(e.Source, ", ")))
+	}
+	return "nilmapper: " + strings.Join(parts, "; ")
+}
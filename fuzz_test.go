@@ -0,0 +1,270 @@
+package nilmapper
+
+import (
+	"reflect"
+	"testing"
+)
+
+// shapes builds struct types and values from fuzz data. Once the data is
+// exhausted every byte reads as zero, so any input gives a valid shape.
+type shapes struct {
+	data []byte
+	pos  int
+}
+
+func (g *shapes) byte() byte {
+	if g.pos >= len(g.data) {
+		return 0
+	}
+	b := g.data[g.pos]
+	g.pos++
+	return b
+}
+
+type fuzzReader interface {
+	Read([]byte) (int, error)
+}
+
+// fieldTypes are the types of the fields of generated structs. The last
+// three entries are a nested generated struct, a pointer to one and a slice
+// of them.
+var fieldTypes = []reflect.Type{
+	typeOf[int](),
+	typeOf[*int](),
+	typeOf[string](),
+	typeOf[*string](),
+	typeOf[Status](),
+	typeOf[*Status](),
+	typeOf[uintptr](),
+	typeOf[*uintptr](),
+	typeOf[float64](),
+	typeOf[bool](),
+	typeOf[map[string]int](),
+	typeOf[*map[string]int](),
+	typeOf[[]int](),
+	typeOf[[]string](),
+	typeOf[[]*int](),
+	typeOf[interface{}](),
+	typeOf[*interface{}](),
+	typeOf[fuzzReader](),
+	nil,
+	nil,
+	nil,
+}
+
+var fieldNames = []string{"A", "B", "C", "D", "E", "F"}
+
+// structType returns a struct type with up to four exported fields.
+func (g *shapes) structType(depth int) reflect.Type {
+	var fields []reflect.StructField
+	used := make(map[string]bool)
+	for n := int(g.byte() % 5); n > 0; n-- {
+		name := fieldNames[int(g.byte())%len(fieldNames)]
+		if used[name] {
+			continue
+		}
+		used[name] = true
+		i := int(g.byte()) % len(fieldTypes)
+		t := fieldTypes[i]
+		if t == nil {
+			if depth >= 2 {
+				t = typeOf[int]()
+			} else {
+				t = g.structType(depth + 1)
+				switch len(fieldTypes) - i {
+				case 2:
+					t = reflect.PtrTo(t)
+				case 1:
+					t = reflect.SliceOf(t)
+				}
+			}
+		}
+		fields = append(fields, reflect.StructField{Name: name, Type: t})
+	}
+	return reflect.StructOf(fields)
+}
+
+// fill sets v to a value read from the data. Pointers, slices, maps and
+// interfaces are nil when the byte read for them is even.
+func (g *shapes) fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(g.byte()%2 == 1)
+	case reflect.Int:
+		v.SetInt(int64(int8(g.byte())))
+	case reflect.Uintptr:
+		v.SetUint(uint64(g.byte()))
+	case reflect.Float64:
+		v.SetFloat(float64(int8(g.byte())) / 4)
+	case reflect.String:
+		v.SetString(string(rune('a' + g.byte()%26)))
+	case reflect.Ptr:
+		if g.byte()%2 == 1 {
+			ptr := reflect.New(v.Type().Elem())
+			g.fill(ptr.Elem())
+			v.Set(ptr)
+		}
+	case reflect.Slice:
+		if b := g.byte(); b%2 == 1 {
+			n := int(b>>1) % 3
+			slice := reflect.MakeSlice(v.Type(), n, n)
+			for i := 0; i < n; i++ {
+				g.fill(slice.Index(i))
+			}
+			v.Set(slice)
+		}
+	case reflect.Map:
+		if g.byte()%2 == 1 {
+			m := reflect.MakeMap(v.Type())
+			m.SetMapIndex(reflect.ValueOf("k"), reflect.ValueOf(int(g.byte())))
+			v.Set(m)
+		}
+	case reflect.Interface:
+		if v.NumMethod() == 0 && g.byte()%2 == 1 {
+			v.Set(reflect.ValueOf(int(g.byte())))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			g.fill(v.Field(i))
+		}
+	}
+}
+
+// noPanic calls fn and fails t if it panics.
+func noPanic(t *testing.T, name string, fn func() error) (err error) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("%s panicked: %v", name, r)
+		}
+	}()
+	return fn()
+}
+
+func FuzzCopy(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{4, 0, 0, 1, 1, 2, 2, 3, 4, 0, 1, 1, 0, 2, 3, 3, 2, 7, 1, 9, 1, 2, 3, 4, 5, 6, 7, 8})
+	f.Add([]byte{2, 0, 18, 1, 1, 2, 0, 19, 1, 1, 3, 5})
+	f.Add([]byte{3, 0, 20, 1, 0, 2, 1, 12, 3, 0, 20, 1, 0, 3, 1, 13, 3, 3, 5, 7, 9})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		g := &shapes{data: data}
+		srcType, destType := g.structType(0), g.structType(0)
+		src := reflect.New(srcType)
+		g.fill(src.Elem())
+		pos := g.pos
+		dest := reflect.New(destType)
+		g.fill(dest.Elem())
+		g.pos = pos
+		before := reflect.New(destType).Elem()
+		g.fill(before)
+
+		noPanic(t, "ValidatePair", func() error { return ValidatePair(srcType, destType) })
+		noPanic(t, "Diff", func() error {
+			_, err := Diff(src.Interface(), dest.Interface())
+			return err
+		})
+		noPanic(t, "CopySlice", func() error {
+			srcs := reflect.MakeSlice(reflect.SliceOf(srcType), 2, 2)
+			srcs.Index(1).Set(src.Elem())
+			return CopySlice(srcs.Interface(), reflect.New(reflect.SliceOf(destType)).Interface())
+		})
+		noPanic(t, "Copy pointer", func() error { return Copy(src.Interface(), reflect.New(destType).Interface()) })
+		err := noPanic(t, "Copy", func() error { return Copy(src.Elem().Interface(), dest.Interface()) })
+		if err != nil {
+			return
+		}
+
+		for i := 0; i < destType.NumField(); i++ {
+			name := destType.Field(i).Name
+			srcField := src.Elem().FieldByName(name)
+			if !srcField.IsValid() {
+				continue
+			}
+			destField := dest.Elem().Field(i)
+			switch {
+			case srcField.Kind() == reflect.Ptr && srcField.IsNil():
+				if !reflect.DeepEqual(destField.Interface(), before.Field(i).Interface()) {
+					t.Errorf("%s: nil source changed %#v into %#v", name, before.Field(i).Interface(), destField.Interface())
+				}
+			case destField.Type() == reflect.PtrTo(srcField.Type()) && srcField.Kind() != reflect.Struct && srcField.Kind() != reflect.Slice:
+				if destField.IsNil() || !reflect.DeepEqual(destField.Elem().Interface(), srcField.Interface()) {
+					t.Errorf("%s: %#v is not wrapped into %#v", name, srcField.Interface(), destField.Interface())
+				}
+			}
+		}
+	})
+}
+
+// TestCopyRegressions covers inputs which made Copy panic.
+func TestCopyRegressions(t *testing.T) {
+	type uintptrs struct {
+		P *uintptr
+		N uintptr
+	}
+	type uintptrsDst struct {
+		P *uintptr
+		N *uintptr
+	}
+	type named struct {
+		Status Status
+		Lookup map[string]int
+		Reader string
+		Any    interface{}
+	}
+	type namedDst struct {
+		Status *Status
+		Lookup *map[string]int
+		Reader fuzzReader
+		Any    *interface{}
+	}
+
+	var x int
+	tests := []struct {
+		name        string
+		copy        func() error
+		wantErr     string
+		wantNoError bool
+	}{
+		{name: "source is not a struct", copy: func() error { return Copy(1, &x) }, wantErr: "nilmapper: source must be a struct or a slice, got int"},
+		{name: "nil destination", copy: func() error { return Copy(named{}, nil) }, wantErr: "nilmapper: destination must be a non-nil pointer, got <nil>"},
+		{name: "destination is not a pointer", copy: func() error { return Copy(named{}, namedDst{}) }, wantErr: "nilmapper: destination must be a non-nil pointer, got nilmapper.namedDst"},
+		{name: "slice into struct", copy: func() error { return Copy([]named{}, &namedDst{}) }, wantErr: "nilmapper: cannot map []nilmapper.named into nilmapper.namedDst"},
+		{name: "struct into slice", copy: func() error { return CopySlice(named{}, &[]namedDst{}) }, wantErr: "nilmapper: cannot map nilmapper.named into []nilmapper.namedDst"},
+		{name: "nil source", copy: func() error { return Copy(nil, &namedDst{}) }, wantNoError: true},
+		{name: "nil slice source", copy: func() error { return CopySlice(nil, &[]namedDst{}) }, wantNoError: true},
+		{name: "nil pointer to a slice", copy: func() error { return CopySlice((*[]named)(nil), &[]namedDst{}) }, wantNoError: true},
+		{name: "struct into int", copy: func() error { return Copy(named{}, &x) }, wantErr: "nilmapper: cannot map nilmapper.named into int"},
+		{name: "uintptr pointers", copy: func() error {
+			p := uintptr(1)
+			return Copy(uintptrs{P: &p, N: 2}, &uintptrsDst{})
+		}, wantNoError: true},
+		{name: "string into interface", copy: func() error {
+			return Copy(named{Status: "on", Lookup: map[string]int{"a": 1}, Reader: "r"}, &namedDst{})
+		}, wantErr: "Reader: nilmapper: cannot assign string to nilmapper.fuzzReader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := noPanic(t, "Copy", tt.copy)
+			if tt.wantNoError {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got error %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	var dst namedDst
+	boxed := interface{}(1)
+	if err := Copy(struct {
+		Status Status
+		Lookup map[string]int
+		Any    *interface{}
+	}{"on", map[string]int{"a": 1}, &boxed}, &dst); err != nil {
+		t.Fatal(err)
+	}
+	if *dst.Status != "on" || (*dst.Lookup)["a"] != 1 || *dst.Any != 1 {
+		t.Errorf("unexpected %v, %v and %v", *dst.Status, *dst.Lookup, *dst.Any)
+	}
+}
@@ -0,0 +1,131 @@
+package nilmapper
+
+import (
+	"context"
+	"reflect"
+)
+
+// BeforeMapper is implemented by source types that check or prepare
+// themselves before their fields are mapped. An error stops the mapping and
+// is returned by Copy.
+type BeforeMapper interface {
+	BeforeMap() error
+}
+
+// AfterMapper is implemented by destination types that normalize themselves
+// once their fields have been mapped from src, for example to trim strings or
+// fill in default values. An error is returned by Copy.
+type AfterMapper interface {
+	AfterMap(src any) error
+}
+
+// BeforeContextMapper is like BeforeMapper, but BeforeMapContext also
+// receives the context of the call, as for ToContextMapper. It takes
+// precedence over BeforeMap.
+type BeforeContextMapper interface {
+	BeforeMapContext(ctx context.Context) error
+}
+
+// AfterContextMapper is like AfterMapper, but AfterMapContext also receives
+// the context of the call, as for ToContextMapper. It takes precedence over
+// AfterMap.
+type AfterContextMapper interface {
+	AfterMapContext(ctx context.Context, src any) error
+}
+
+var (
+	beforeMapperType        = typeOf[BeforeMapper]()
+	afterMapperType         = typeOf[AfterMapper]()
+	beforeContextMapperType = typeOf[BeforeContextMapper]()
+	afterContextMapperType  = typeOf[AfterContextMapper]()
+)
+
+// hookFunc is a hook registered on a Config, in its untyped form.
+type hookFunc func(srcValue reflect.Value, destValue reflect.Value) error
+
+// BeforeMap registers a hook which is called with the source value before
+// it is mapped into a Dst, after the BeforeMap method of the source if it has
+// one.
+func (c *Config[Src, Dst]) BeforeMap(hook func(src Src) error) *Config[Src, Dst] {
+	c.tm.before = append(c.tm.before, func(srcValue reflect.Value, _ reflect.Value) error {
+		return hook(valueAs[Src](srcValue))
+	})
+	return c
+}
+
+// AfterMap registers a hook which is called with the source value and the
+// destination once the mapping is done, after the AfterMap method of the
+// destination if it has one.
+func (c *Config[Src, Dst]) AfterMap(hook func(src Src, dst *Dst) error) *Config[Src, Dst] {
+	c.tm.after = append(c.tm.after, func(srcValue reflect.Value, destValue reflect.Value) error {
+		return hook(valueAs[Src](srcValue), destValue.Addr().Interface().(*Dst))
+	})
+	return c
+}
+
+// beforeMap calls the BeforeMap or BeforeMapContext method of the source and
+// the before hooks of tm, which may be nil.
+func (r *run) beforeMap(tm *typeMap, srcValue reflect.Value, destValue reflect.Value) error {
+	if src, ok := hookReceiver(srcValue, beforeContextMapperType).(BeforeContextMapper); ok {
+		if err := src.BeforeMapContext(r.ctx); err != nil {
+			return err
+		}
+	} else if src, ok := hookReceiver(srcValue, beforeMapperType).(BeforeMapper); ok {
+		if err := src.BeforeMap(); err != nil {
+			return err
+		}
+	}
+	if tm != nil {
+		for _, hook := range tm.before {
+			if err := hook(srcValue, destValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// afterMap calls the AfterMap or AfterMapContext method of the destination
+// and the after hooks of tm, which may be nil.
+func (r *run) afterMap(tm *typeMap, srcValue reflect.Value, destValue reflect.Value) error {
+	if dest, ok := hookReceiver(destValue, afterContextMapperType).(AfterContextMapper); ok && srcValue.CanInterface() {
+		if err := dest.AfterMapContext(r.ctx, srcValue.Interface()); err != nil {
+			return err
+		}
+	} else if dest, ok := hookReceiver(destValue, afterMapperType).(AfterMapper); ok && srcValue.CanInterface() {
+		if err := dest.AfterMap(srcValue.Interface()); err != nil {
+			return err
+		}
+	}
+	if tm != nil {
+		for _, hook := range tm.after {
+			if err := hook(srcValue, destValue); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hookReceiver returns v if it implements iface, or else a pointer to v, or
+// to a copy of v if it cannot be addressed, if that pointer implements
+// iface, so that methods with either receiver are found. It returns nil
+// otherwise, and for values which cannot be used as an interface.
+func hookReceiver(v reflect.Value, iface reflect.Type) interface{} {
+	if !v.CanInterface() {
+		return nil
+	}
+	if v.Type().Implements(iface) {
+		return v.Interface()
+	}
+	if v.Kind() != reflect.Ptr && reflect.PtrTo(v.Type()).Implements(iface) {
+		return addressable(v).Addr().Interface()
+	}
+	return nil
+}
+
+// implements reports whether t or *t implements iface, which is where
+// hookReceiver looks for methods.
+func implements(t reflect.Type, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PtrTo(t).Implements(iface)
+}
@@ -0,0 +1,133 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type SignupSrc struct {
+	Name  string
+	Email string
+}
+
+func (s SignupSrc) BeforeMap() error {
+	if s.Email == "" {
+		return errors.New("email is required")
+	}
+	return nil
+}
+
+// Consent checks itself with a pointer receiver.
+type Consent struct {
+	Accepted bool
+}
+
+func (c *Consent) BeforeMap() error {
+	if !c.Accepted {
+		return errors.New("consent is required")
+	}
+	return nil
+}
+
+// TenantDst is stamped with the tenant of the context once mapped.
+type TenantDst struct {
+	Name   string
+	Tenant string
+}
+
+func (d *TenantDst) AfterMapContext(ctx context.Context, src any) error {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	if !ok {
+		return errors.New("no tenant")
+	}
+	d.Tenant = tenant
+	return nil
+}
+
+type SignupDst struct {
+	Name    string
+	Email   string
+	Country string
+}
+
+func (d *SignupDst) AfterMap(src any) error {
+	d.Email = strings.ToLower(strings.TrimSpace(src.(SignupSrc).Email))
+	if d.Country == "" {
+		d.Country = "NL"
+	}
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	t.Run("Interfaces", func(t *testing.T) {
+		var dest SignupDst
+		assert.Equal(t, Copy(SignupSrc{Name: "Ada", Email: " Ada@Example.com "}, &dest), nil)
+		assert.Equal(t, dest, SignupDst{Name: "Ada", Email: "ada@example.com", Country: "NL"})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		var dest []SignupDst
+		err := CopySlice([]SignupSrc{{Email: "a@example.com"}, {Name: "Ada"}}, &dest)
+		assert.Equal(t, err.Error(), "nilmapper: index 1: email is required")
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		var dest struct{ Signup *SignupDst }
+		assert.Equal(t, Copy(struct{ Signup SignupSrc }{Signup: SignupSrc{Email: "A@B.C"}}, &dest), nil)
+		assert.Equal(t, dest.Signup.Email, "a@b.c")
+	})
+
+	t.Run("Pointer receiver", func(t *testing.T) {
+		type form struct{ Consent Consent }
+		var dest struct{ Consent struct{ Accepted bool } }
+		// Neither the source passed by value nor its fields can be addressed.
+		assert.Equal(t, Copy(form{}, &dest).Error(), "Consent: consent is required")
+		assert.Equal(t, Copy(Consent{}, &dest.Consent).Error(), "consent is required")
+		assert.Equal(t, Copy(form{Consent: Consent{Accepted: true}}, &dest), nil)
+		assert.Equal(t, dest.Consent.Accepted, true)
+	})
+}
+
+func TestContextHooks(t *testing.T) {
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	var dest TenantDst
+	assert.Equal(t, CopyContext(ctx, SignupSrc{Name: "Ada", Email: "a@example.com"}, &dest), nil)
+	assert.Equal(t, dest, TenantDst{Name: "Ada", Tenant: "acme"})
+	assert.Equal(t, Copy(SignupSrc{Email: "a@example.com"}, &dest).Error(), "no tenant")
+}
+
+func TestMapperHooks(t *testing.T) {
+	m := New()
+	var calls []string
+	cfg := ConfigureOn[SignupSrc, SignupDst](m).
+		BeforeMap(func(src SignupSrc) error {
+			calls = append(calls, "before "+src.Name)
+			return nil
+		}).
+		AfterMap(func(src SignupSrc, dst *SignupDst) error {
+			calls = append(calls, "after "+dst.Email)
+			dst.Country = "BE"
+			return nil
+		})
+	assert.Equal(t, cfg.Err(), nil)
+
+	var dest SignupDst
+	assert.Equal(t, m.Copy(SignupSrc{Name: "Ada", Email: "ADA@EXAMPLE.COM"}, &dest), nil)
+	assert.Equal(t, calls, []string{"before Ada", "after ada@example.com"})
+	assert.Equal(t, dest.Country, "BE")
+
+	// The default mapper does not know about the hooks registered on m.
+	dest = SignupDst{}
+	assert.Equal(t, Copy(SignupSrc{Name: "Ada", Email: "ADA@EXAMPLE.COM"}, &dest), nil)
+	assert.Equal(t, len(calls), 2)
+	assert.Equal(t, dest.Country, "NL")
+
+	ConfigureOn[SignupSrc, SignupDst](m).AfterMap(func(src SignupSrc, dst *SignupDst) error {
+		return errors.New("rejected")
+	})
+	assert.Equal(t, m.Copy(SignupSrc{Email: "a@example.com"}, &dest).Error(), "rejected")
+}
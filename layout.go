@@ -0,0 +1,163 @@
+package nilmapper
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// layout is how mapFields copies a struct into a struct type with the same
+// fields, in the same order, with the same names and types. Fields holding no
+// pointers are assigned directly, which is what mapping them field by field
+// amounts to, and the other ones are mapped by mapField so that pointers are
+// still copied deeply.
+type layout struct {
+	// whole is set when the types are the same and every field is flat, so
+	// that values are assigned as a whole.
+	whole bool
+	// flat are the indexes of the fields which are assigned.
+	flat []int
+	// deep are the destination fields which are mapped by mapField.
+	deep []reflect.StructField
+	// pairs are the struct types found in flat fields. Their configurations
+	// would be bypassed by the assignment, so the layout is not used once
+	// one of them is configured.
+	pairs []typePair
+}
+
+// layoutOf returns the layout of srcType and destType, or nil if they do not
+// have the same fields or map themselves. Layouts are computed once per pair
+// and cached on the Mapper.
+func (m *Mapper) layoutOf(srcType reflect.Type, destType reflect.Type) *layout {
+	pair := typePair{src: srcType, dst: destType}
+	m.layoutsMu.RLock()
+	l, ok := m.layouts[pair]
+	m.layoutsMu.RUnlock()
+	if ok {
+		return l
+	}
+	l = newLayout(srcType, destType)
+	m.layoutsMu.Lock()
+	if m.layouts == nil {
+		m.layouts = make(map[typePair]*layout)
+	}
+	m.layouts[pair] = l
+	m.layoutsMu.Unlock()
+	return l
+}
+
+func newLayout(srcType reflect.Type, destType reflect.Type) *layout {
+	if srcType.NumField() != destType.NumField() || selfMappable(srcType, destType) || hasHooks(srcType, destType) {
+		return nil
+	}
+	l := &layout{whole: srcType == destType}
+	for i := 0; i < srcType.NumField(); i++ {
+		srcField, destField := srcType.Field(i), destType.Field(i)
+		if srcField.Name != destField.Name || srcField.Type != destField.Type || srcField.Anonymous != destField.Anonymous {
+			return nil
+		}
+		if !srcField.IsExported() {
+			// Unexported fields are skipped, or need CopyUnexported, which
+			// does not use layouts.
+			l.whole = false
+			continue
+		}
+		if flatType(srcField.Type, &l.pairs) {
+			l.flat = append(l.flat, i)
+		} else {
+			l.whole = false
+			l.deep = append(l.deep, destField)
+		}
+	}
+	return l
+}
+
+// hasHooks reports whether srcType has a BeforeMap method or destType an
+// AfterMap method, or one of their context variants.
+func hasHooks(srcType reflect.Type, destType reflect.Type) bool {
+	return implements(srcType, beforeMapperType) || implements(srcType, beforeContextMapperType) ||
+		implements(destType, afterMapperType) || implements(destType, afterContextMapperType)
+}
+
+// flatType reports whether mapping a value of type t into a value of the same
+// type amounts to assigning it, because it holds no pointers and is not
+// mapped by methods or hooks. The struct types found in t are added to pairs.
+func flatType(t reflect.Type, pairs *[]typePair) bool {
+	if selfMappable(t, t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		// Arrays are assigned as a whole by mapField.
+		return pointerFree(t.Elem())
+	case reflect.Struct:
+		if hasHooks(t, t) {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); !field.IsExported() || !flatType(field.Type, pairs) {
+				return false
+			}
+		}
+		*pairs = append(*pairs, typePair{src: t, dst: t})
+		return true
+	}
+	return false
+}
+
+// pointerFree reports whether values of type t hold no pointers, other than
+// the immutable contents of strings.
+func pointerFree(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Array:
+		return pointerFree(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !pointerFree(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	case reflect.Ptr, reflect.UnsafePointer, reflect.Slice, reflect.Map,
+		reflect.Interface, reflect.Chan, reflect.Func:
+		return false
+	}
+	return true
+}
+
+// mapLayout maps srcValue into destValue, whose types have the layout l. It
+// reports false, without mapping anything, when one of the struct types of
+// the flat fields of l is configured.
+func (r *run) mapLayout(l *layout, srcValue reflect.Value, destValue reflect.Value) (bool, error) {
+	for _, pair := range l.pairs {
+		if r.lookupTypeMap(pair.src, pair.dst) != nil {
+			return false, nil
+		}
+	}
+	if l.whole {
+		destValue.Set(srcValue)
+		return true, nil
+	}
+	for _, i := range l.flat {
+		destValue.Field(i).Set(srcValue.Field(i))
+	}
+	for _, destField := range l.deep {
+		srcFieldValue, destFieldValue := srcValue.Field(destField.Index[0]), destValue.Field(destField.Index[0])
+		var err error
+		r.enter(destField.Name)
+		if spec := mergeSpecOf(nil, destField); spec != nil {
+			err = r.mergeSlice(srcFieldValue, destFieldValue, spec)
+		} else {
+			err = r.mapField(srcFieldValue, destFieldValue, nil)
+		}
+		r.leave()
+		if err != nil {
+			return true, fmt.Errorf("%s: %w", destField.Name, err)
+		}
+	}
+	return true, nil
+}
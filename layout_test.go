@@ -0,0 +1,115 @@
+package nilmapper
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type layoutPoint struct {
+	X, Y int
+}
+
+type layoutSrc struct {
+	ID     int
+	Name   string
+	Point  layoutPoint
+	Grid   [2]layoutPoint
+	Email  *string
+	Tags   []string
+	hidden string
+}
+
+type layoutDst struct {
+	ID     int
+	Name   string
+	Point  layoutPoint
+	Grid   [2]layoutPoint
+	Email  *string
+	Tags   []string
+	hidden string
+}
+
+type layoutHooked struct {
+	ID   int
+	Name string
+}
+
+func (h *layoutHooked) AfterMap(src any) error {
+	h.Name = strings.ToUpper(h.Name)
+	return nil
+}
+
+func TestLayout(t *testing.T) {
+	assert.Equal(t, defaultMapper.layoutOf(typeOf[layoutSrc](), typeOf[struct{ ID int }]()), (*layout)(nil))
+	assert.Equal(t, defaultMapper.layoutOf(typeOf[layoutSrc](), typeOf[layoutHooked]()), (*layout)(nil))
+	l := defaultMapper.layoutOf(typeOf[layoutSrc](), typeOf[layoutDst]())
+	assert.Equal(t, l.whole, false)
+	assert.Equal(t, l.flat, []int{0, 1, 2, 3})
+	assert.Equal(t, len(l.deep), 2)
+	assert.Equal(t, defaultMapper.layoutOf(typeOf[layoutPoint](), typeOf[layoutPoint]()).whole, true)
+
+	email := "ada@example.com"
+	src := layoutSrc{
+		ID:     1,
+		Name:   "Ada",
+		Point:  layoutPoint{1, 2},
+		Grid:   [2]layoutPoint{{3, 4}, {5, 6}},
+		Email:  &email,
+		Tags:   []string{"a"},
+		hidden: "hidden",
+	}
+	var dst layoutDst
+	assert.Equal(t, Copy(src, &dst), nil)
+	assert.Equal(t, dst, layoutDst{ID: 1, Name: "Ada", Point: layoutPoint{1, 2}, Grid: src.Grid, Email: &email, Tags: []string{"a"}})
+	if dst.Email == src.Email || &dst.Tags[0] == &src.Tags[0] {
+		t.Error("pointers and slices are shared with the source")
+	}
+
+	// Nil source pointers still leave the destination untouched.
+	assert.Equal(t, Copy(layoutSrc{ID: 2}, &dst), nil)
+	assert.Equal(t, *dst.Email, email)
+	assert.Equal(t, dst.ID, 2)
+
+	var hooked layoutHooked
+	assert.Equal(t, Copy(layoutHooked{ID: 1, Name: "ada"}, &hooked), nil)
+	assert.Equal(t, hooked, layoutHooked{ID: 1, Name: "ADA"})
+}
+
+func TestLayoutConfigured(t *testing.T) {
+	m := New()
+	src := layoutSrc{Point: layoutPoint{1, 2}}
+	var dst layoutDst
+	assert.Equal(t, m.Copy(src, &dst), nil)
+	assert.Equal(t, dst.Point, layoutPoint{1, 2})
+
+	// Configurations registered once the layout is cached still apply.
+	ConfigureOn[layoutPoint, layoutPoint](m).Ignore("Y")
+	dst = layoutDst{}
+	assert.Equal(t, m.Copy(src, &dst), nil)
+	assert.Equal(t, dst.Point, layoutPoint{X: 1})
+
+	ConfigureOn[layoutSrc, layoutDst](m).Ignore("Name")
+	dst = layoutDst{}
+	assert.Equal(t, m.Copy(layoutSrc{Name: "Ada"}, &dst), nil)
+	assert.Equal(t, dst.Name, "")
+}
+
+func TestLayoutAllocations(t *testing.T) {
+	r := New().newRun(context.Background(), nil)
+	src := reflect.ValueOf(benchFlat{ID: 1, Name: "Ada"})
+	same := reflect.New(typeOf[benchFlat]()).Elem()
+	dto := reflect.New(typeOf[benchFlatDTO]()).Elem()
+	for _, dst := range []reflect.Value{same, dto} {
+		allocs := testing.AllocsPerRun(100, func() {
+			if err := r.mapFields(src, dst, nil); err != nil {
+				t.Fatal(err)
+			}
+		})
+		assert.Equal(t, allocs, float64(0))
+	}
+	assert.Equal(t, dto.Interface(), benchFlatDTO{ID: 1, Name: "Ada"})
+}
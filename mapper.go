@@ -1,10 +1,148 @@
 package nilmapper
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
+// Mapper holds the mapping configurations and hooks of a set of type pairs.
+// The package level functions use a default Mapper, while New creates an
+// independent one, for example to map the same types differently in two
+// places of an application.
+type Mapper struct {
+	mu   sync.RWMutex
+	maps map[typePair]*typeMap
+	opts options
+
+	layoutsMu sync.RWMutex
+	layouts   map[typePair]*layout
+}
+
+// New returns an empty Mapper using opts for every call.
+func New(opts ...Option) *Mapper {
+	return &Mapper{maps: make(map[typePair]*typeMap), opts: options{matcher: CaseInsensitive}.with(opts)}
+}
+
+var defaultMapper = New()
+
+// run holds the state of a single Copy or CopySlice call.
+type run struct {
+	*Mapper
+	options
+	// ctx is passed to context-aware resolvers and stops CopySlice when it
+	// is done.
+	ctx context.Context
+	// path holds the field names and slice indexes leading from the mapped
+	// value to the one being mapped.
+	path      []string
+	unmatched UnmatchedFieldsError
+	seen      map[string]bool
+	fields    fieldIndexes
+	// quiet counts the values being reported as a whole, inside which
+	// nothing is reported.
+	quiet int
+}
+
+// runs recycles the runs released by Copy and CopySlice, so that mapping
+// structs with the same layout does not allocate.
+var runs = sync.Pool{New: func() interface{} { return new(run) }}
+
+func (m *Mapper) newRun(ctx context.Context, opts []Option) *run {
+	r := runs.Get().(*run)
+	r.Mapper, r.options, r.ctx = m, m.opts, ctx
+	r.options.apply(opts)
+	r.fields.matcher, r.fields.srcTags, r.fields.destTags = r.matcher, r.srcTags, r.destTags
+	r.fields.exportedOnly = !r.copyUnexported
+	if r.report != nil {
+		*r.report = Report{}
+	}
+	return r
+}
+
+// release puts r back into runs. r must not be used afterwards.
+func (r *run) release() {
+	*r = run{path: r.path[:0]}
+	runs.Put(r)
+}
+
+// finish returns the error collected while mapping, if any.
+func (r *run) finish() error {
+	if len(r.unmatched.Destination) > 0 || len(r.unmatched.Source) > 0 {
+		unmatched := r.unmatched
+		return &unmatched
+	}
+	return nil
+}
+
+func (r *run) enter(segment string) {
+	r.path = append(r.path, segment)
+}
+
+func (r *run) leave() {
+	r.path = r.path[:len(r.path)-1]
+}
+
+// fieldPath returns the path of the field name of the value being mapped,
+// without slice indexes.
+func (r *run) fieldPath(name string) string {
+	var b strings.Builder
+	for _, segment := range r.path {
+		if strings.HasPrefix(segment, "[") {
+			continue
+		}
+		b.WriteString(segment)
+		b.WriteByte('.')
+	}
+	b.WriteString(name)
+	return b.String()
+}
+
+func (r *run) unmatchedField(list *[]string, side string, name string) {
+	r.addUnmatched(list, side, r.fieldPath(name))
+}
+
+func (r *run) addUnmatched(list *[]string, side string, path string) {
+	if !r.seen[side+path] {
+		if r.seen == nil {
+			r.seen = make(map[string]bool)
+		}
+		r.seen[side+path] = true
+		*list = append(*list, path)
+	}
+}
+
+// fork returns a run with the same options and position as r, which can map
+// concurrently with r.
+func (r *run) fork() *run {
+	f := &run{Mapper: r.Mapper, options: r.options, ctx: r.ctx}
+	f.path = append(f.path, r.path...)
+	f.fields.matcher, f.fields.srcTags, f.fields.destTags = r.matcher, r.srcTags, r.destTags
+	f.fields.exportedOnly = !r.copyUnexported
+	if r.report != nil {
+		f.report = &Report{}
+	}
+	return f
+}
+
+// join adds the unmatched fields and the report of a forked run to r.
+func (r *run) join(f *run) {
+	if r.report != nil {
+		r.report.Written = append(r.report.Written, f.report.Written...)
+		r.report.SkippedNil = append(r.report.SkippedNil, f.report.SkippedNil...)
+		r.report.Unmatched = append(r.report.Unmatched, f.report.Unmatched...)
+	}
+	for _, path := range f.unmatched.Destination {
+		r.addUnmatched(&r.unmatched.Destination, "dest", path)
+	}
+	for _, path := range f.unmatched.Source {
+		r.addUnmatched(&r.unmatched.Source, "src", path)
+	}
+}
+
 // The CopySlice function maps a slice of source struct values to a slice of
 // destination struct values.
 // It takes two parameters - source and destination - both of which are interfaces.
@@ -57,29 +195,148 @@ import (
 //		// Test2 456 Value
 //	}
 
-func CopySlice(source interface{}, destination interface{}) {
-	srcValue := reflect.ValueOf(source)
-	destValue := reflect.ValueOf(destination).Elem()
-	mapSlice(srcValue, destValue)
+func CopySlice(source interface{}, destination interface{}, opts ...Option) error {
+	return defaultMapper.CopySliceContext(context.Background(), source, destination, opts...)
+}
 
+// CopySliceContext is like CopySlice, but stops as soon as ctx is done and
+// returns ctx.Err() wrapped with the index reached. ctx is also passed to
+// resolvers taking a context.Context, so request scoped values such as the
+// locale or the tenant reach them.
+func CopySliceContext(ctx context.Context, source interface{}, destination interface{}, opts ...Option) error {
+	return defaultMapper.CopySliceContext(ctx, source, destination, opts...)
 }
 
-func mapSlice(srcValue reflect.Value, destValue reflect.Value) {
+// CopySlice is like the package level CopySlice, but uses the
+// configurations, hooks and options of m.
+func (m *Mapper) CopySlice(source interface{}, destination interface{}, opts ...Option) error {
+	return m.CopySliceContext(context.Background(), source, destination, opts...)
+}
+
+// CopySliceContext is like the package level CopySliceContext, but uses the
+// configurations, hooks and options of m.
+func (m *Mapper) CopySliceContext(ctx context.Context, source interface{}, destination interface{}, opts ...Option) error {
+	if err := checkDestination(destination); err != nil {
+		return err
+	}
+	r := m.newRun(ctx, opts)
+	defer r.release()
+	srcValue := reflect.ValueOf(source)
+	destValue := reflect.ValueOf(r.target(destination)).Elem()
+	if err := r.mapSlice(srcValue, destValue); err != nil {
+		return err
+	}
+	return r.finish()
+}
 
+func (r *run) mapSlice(srcValue reflect.Value, destValue reflect.Value) error {
+	if srcValue.Kind() == reflect.Ptr && srcValue.Type().Elem().Kind() == reflect.Slice {
+		srcValue = srcValue.Elem()
+	}
+	if !srcValue.IsValid() {
+		// Like a nil struct, a nil slice leaves the destination untouched.
+		return nil
+	}
+	if srcValue.Kind() != reflect.Slice || destValue.Kind() != reflect.Slice {
+		return fmt.Errorf("nilmapper: cannot map %s into %s", srcValue.Type(), destValue.Type())
+	}
 	srcLen := srcValue.Len()
-	destType := destValue.Type().Elem()
-	destSlice := reflect.MakeSlice(destValue.Type(), srcLen, srcLen)
-	for i := 0; i < srcLen; i++ {
+	target := r.sliceTarget(destValue, srcLen)
+	var err error
+	if r.parallelism > 1 && srcLen > 1 {
+		err = r.mapParallel(srcValue, target)
+	} else {
+		err = r.mapRange(srcValue, target, 0, srcLen)
+	}
+	if err != nil {
+		return err
+	}
+	destValue.Set(target.slice)
+	return nil
+}
+
+// mapRange maps the elements of srcValue from index lo up to hi into the
+// target slice.
+func (r *run) mapRange(srcValue reflect.Value, target sliceTarget, lo int, hi int) error {
+	destType := target.slice.Type().Elem()
+	for i := lo; i < hi; i++ {
+		if err := r.ctx.Err(); err != nil {
+			return fmt.Errorf("nilmapper: index %d: %w", i, err)
+		}
 		srcElem := srcValue.Index(i)
 		destElem := reflect.New(destType).Elem()
-		mapStruct(srcElem.Interface(), destElem.Addr().Interface(), false)
-		destSlice.Index(i).Set(destElem)
+		if i < target.merged {
+			destElem.Set(target.slice.Index(i))
+		}
+		r.enter(fmt.Sprintf("[%d]", i))
+		err := r.mapStruct(srcElem.Interface(), destElem.Addr().Interface())
+		r.leave()
+		if err != nil {
+			return fmt.Errorf("nilmapper: index %d: %w", i, err)
+		}
+		target.slice.Index(target.offset + i).Set(destElem)
+	}
+	return nil
+}
+
+// mapParallel splits srcValue in consecutive ranges mapped by up to
+// r.parallelism goroutines. Once an element fails, the ranges stop before
+// the indexes above it, and only the error of the lowest index is returned,
+// which is the one mapping sequentially returns.
+func (r *run) mapParallel(srcValue reflect.Value, target sliceTarget) error {
+	srcLen := srcValue.Len()
+	size := (srcLen + r.parallelism - 1) / r.parallelism
+	workers := make([]*run, (srcLen+size-1)/size)
+	errs := make([]error, len(workers))
+	// failed is the lowest index which failed so far.
+	var failed atomic.Int64
+	failed.Store(int64(srcLen))
+	var wg sync.WaitGroup
+	for w := range workers {
+		lo, hi := w*size, (w+1)*size
+		if hi > srcLen {
+			hi = srcLen
+		}
+		workers[w] = r.fork()
+		wg.Add(1)
+		go func(w int, lo int, hi int) {
+			defer wg.Done()
+			for i := lo; i < hi && int64(i) < failed.Load(); i++ {
+				if err := workers[w].mapRange(srcValue, target, i, i+1); err != nil {
+					errs[w] = err
+					storeMin(&failed, int64(i))
+					return
+				}
+			}
+		}(w, lo, hi)
+	}
+	wg.Wait()
+	// The ranges are in index order, so the first error is the lowest.
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	for _, worker := range workers {
+		r.join(worker)
+	}
+	return nil
+}
+
+// storeMin sets v to n if n is lower.
+func storeMin(v *atomic.Int64, n int64) {
+	for old := v.Load(); n < old; old = v.Load() {
+		if v.CompareAndSwap(old, n) {
+			return
+		}
 	}
-	destValue.Set(destSlice)
 }
 
 // Copy maps the fields of a source struct or slice to a destination struct or slice.
-// If nested is true, it recursively maps nested structs or slices.
+// Mappings registered with Configure for the source and destination types
+// (or for any nested pair of types) are applied along the way, and an error is
+// returned if one of them is invalid. Options passed to Copy apply on top of
+// the options of the Mapper.
 //
 // Example usage:
 //
@@ -106,277 +363,414 @@ func mapSlice(srcValue reflect.Value, destValue reflect.Value) {
 //
 //	fmt.Println(dest.FieldA, dest.FieldB, dest.FieldC)
 //	// Output: Test1 123 ""
-func Copy(source interface{}, destination interface{}) {
-	mapStruct(source, destination, false)
+func Copy(source interface{}, destination interface{}, opts ...Option) error {
+	return defaultMapper.CopyContext(context.Background(), source, destination, opts...)
+}
+
+// CopyContext is like Copy, but passes ctx to resolvers taking a
+// context.Context, and stops mapping slices as soon as ctx is done.
+func CopyContext(ctx context.Context, source interface{}, destination interface{}, opts ...Option) error {
+	return defaultMapper.CopyContext(ctx, source, destination, opts...)
+}
+
+// Copy is like the package level Copy, but uses the configurations, hooks
+// and options of m.
+func (m *Mapper) Copy(source interface{}, destination interface{}, opts ...Option) error {
+	return m.CopyContext(context.Background(), source, destination, opts...)
+}
+
+// CopyContext is like the package level CopyContext, but uses the
+// configurations, hooks and options of m.
+func (m *Mapper) CopyContext(ctx context.Context, source interface{}, destination interface{}, opts ...Option) error {
+	if err := checkDestination(destination); err != nil {
+		return err
+	}
+	r := m.newRun(ctx, opts)
+	defer r.release()
+	if err := r.mapStruct(source, r.target(destination)); err != nil {
+		return err
+	}
+	return r.finish()
 }
 
-func mapStruct(source interface{}, destination interface{}, nested bool) {
+func (r *run) mapStruct(source interface{}, destination interface{}) error {
+	if err := checkDestination(destination); err != nil {
+		return err
+	}
 	srcValue := reflect.ValueOf(source)
-	srcValue2 := reflect.TypeOf(source)
 	destValue := reflect.ValueOf(destination).Elem()
-	if srcValue.Kind() == reflect.Slice || destValue.Kind() == reflect.Slice && !nested {
-		mapSlice(srcValue, destValue)
-		return
+	if !srcValue.IsValid() {
+		return nil
+	}
+	if srcValue.Kind() == reflect.Slice || destValue.Kind() == reflect.Slice {
+		return r.mapSlice(srcValue, destValue)
 	}
-	var size int
-	if nested {
-		if srcValue.Kind() == reflect.Ptr {
-			srcValue = srcValue.Elem()
+	if srcValue.Kind() == reflect.Ptr {
+		if srcValue.IsNil() {
+			return nil
 		}
-		size = srcValue.NumField()
-	} else {
-		if srcValue.Kind() == reflect.Ptr {
-			srcValue = srcValue.Elem()
+		srcValue = srcValue.Elem()
+	}
+	if handled, err := r.mapSelf(srcValue, destValue); handled || err != nil {
+		return err
+	}
+	if destValue.Kind() == reflect.Ptr && destValue.Type().Elem().Kind() == reflect.Struct {
+		if destValue.IsNil() {
+			destValue.Set(reflect.New(destValue.Type().Elem()))
 		}
-		size = srcValue.NumField()
+		destValue = destValue.Elem()
 	}
-	for i := 0; i < size; i++ {
-		var name string
-		if srcValue2.Kind() == reflect.Ptr {
-			srcFileTypeName := srcValue2.Elem().Field(i)
-			name = srcFileTypeName.Name
-		} else {
-			srcFileTypeName := srcValue2.Field(i)
-			name = srcFileTypeName.Name
+	if srcValue.Kind() != reflect.Struct {
+		return fmt.Errorf("nilmapper: source must be a struct or a slice, got %s", srcValue.Type())
+	}
+	if destValue.Kind() != reflect.Struct {
+		return fmt.Errorf("nilmapper: cannot map %s into %s", srcValue.Type(), destValue.Type())
+	}
+	return r.mapFields(srcValue, destValue, nil)
+}
+
+// checkDestination returns an error unless destination is a non-nil
+// pointer.
+func checkDestination(destination interface{}) error {
+	if v := reflect.ValueOf(destination); v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("nilmapper: destination must be a non-nil pointer, got %T", destination)
+	}
+	return nil
+}
+
+// mapFields copies every field of srcValue into the matching field of
+// destValue. Destination fields listed in skip, or claimed by the
+// configuration registered for the type pair, are left to the configuration.
+func (r *run) mapFields(srcValue reflect.Value, destValue reflect.Value, skip skipSet) error {
+	tm := r.lookupTypeMap(srcValue.Type(), destValue.Type())
+	if tm != nil {
+		if tm.err != nil {
+			return tm.err
+		}
+		skip = append(skip, tm.skip)
+	}
+	if tm == nil && len(skip) == 0 && len(r.srcTags) == 0 && len(r.destTags) == 0 && !r.copyUnexported && !r.recording() {
+		if l := r.layoutOf(srcValue.Type(), destValue.Type()); l != nil {
+			if handled, err := r.mapLayout(l, srcValue, destValue); handled {
+				return err
+			}
 		}
+	}
+	if err := r.beforeMap(tm, srcValue, destValue); err != nil {
+		return err
+	}
 
+	var matched map[string]bool
+	if r.requireDestination || r.recording() {
+		matched = make(map[string]bool)
+	}
+	if r.copyUnexported {
+		srcValue = addressable(srcValue)
+	}
+	srcType := srcValue.Type()
+	destType := destValue.Type()
+	matches := r.fields.matches(srcType, destType)
+	for i := 0; i < srcValue.NumField(); i++ {
+		srcField := srcType.Field(i)
+		name := srcField.Name
 		srcFieldValue := srcValue.Field(i)
-
-		destFieldValue := destValue.FieldByName(name)
-		if !destFieldValue.IsValid() {
-			destFieldValue = destValue.FieldByNameFunc(func(s string) bool {
-				if strings.ToLower(s) == strings.ToLower(name) {
-					return true
-				}
-				return false
-			})
-			if !destFieldValue.IsValid() {
+		if !srcField.IsExported() {
+			// Reading unexported fields through reflect panics, so they are
+			// skipped unless CopyUnexported is given.
+			if !r.copyUnexported {
 				continue
 			}
+			srcFieldValue = exposed(srcFieldValue)
 		}
-		if !destFieldValue.CanSet() {
+		if _, ignored := fieldName(srcField, r.srcTags); ignored {
 			continue
 		}
-		//destFieldValue := destValue.Field(i)
 
-		srcFieldType := srcFieldValue.Type()
-		destFieldType := destFieldValue.Type()
+		if matches[i].err != nil {
+			return matches[i].err
+		}
+		destField := matches[i].field
+		ok := matches[i].match != ""
+		var destFieldValue reflect.Value
+		if ok {
+			var err error
+			destFieldValue, err = destValue.FieldByIndexErr(destField.Index)
+			if err == nil && r.copyUnexported && !destFieldValue.CanSet() && destFieldValue.CanAddr() {
+				destFieldValue = exposed(destFieldValue)
+			}
+			// A field whose value cannot be converted is left untouched, so
+			// it is not matched either. Interfaces which the value does not
+			// implement make mapField fail instead.
+			ok = err == nil && destFieldValue.CanSet() &&
+				(mappable(srcField.Type, destField.Type) || indirectType(destField.Type).Kind() == reflect.Interface)
+		}
+		if !ok {
+			if r.requireSource && srcField.IsExported() && !parseTag(srcField).optional && (tm == nil || !tm.used[name]) {
+				r.unmatchedField(&r.unmatched.Source, "src", name)
+			}
+			continue
+		}
+		if matched != nil {
+			matched[destField.Name] = true
+		}
+		if skip.has(destField.Name) {
+			continue
+		}
 
-		if srcFieldType.Kind() == reflect.Ptr {
-			srcFieldType = srcFieldType.Elem()
+		r.enter(destField.Name)
+		whole := r.beginField(srcFieldValue, destFieldValue)
+		var err error
+		if spec := mergeSpecOf(tm, destField); spec != nil {
+			err = r.mergeSlice(srcFieldValue, destFieldValue, spec)
+		} else {
+			err = r.mapField(srcFieldValue, destFieldValue, skip.child(destField.Name))
+		}
+		r.endField(whole, err)
+		r.leave()
+		if err != nil {
+			return fmt.Errorf("%s: %w", destField.Name, err)
+		}
+	}
+	if matched != nil {
+		for _, destField := range unmatchedFields(destType, matched, skip, r.destTags) {
+			if r.recording() {
+				r.report.Unmatched = append(r.report.Unmatched, r.reportPath(destField.Name))
+			}
+			if !r.requireDestination || parseTag(destField).optional {
+				continue
+			}
+			r.unmatchedField(&r.unmatched.Destination, "dest", destField.Name)
 		}
+	}
 
-		if destFieldType.Kind() == reflect.Ptr {
-			destFieldType = destFieldType.Elem()
+	if tm != nil {
+		if err := tm.apply(r, srcValue, destValue); err != nil {
+			return err
 		}
+	}
+	return r.afterMap(tm, srcValue, destValue)
+}
+
+// unmatchedFields returns the exported fields of destType, including promoted
+// ones, which are neither in matched, nor promoted from a field in matched,
+// nor covered by skip, nor ignored by the struct tags of tagKeys.
+func unmatchedFields(destType reflect.Type, matched map[string]bool, skip skipSet, tagKeys []string) []reflect.StructField {
+	var fields []reflect.StructField
+	for _, destField := range reflect.VisibleFields(destType) {
+		if destField.Anonymous || !destField.IsExported() || matched[destField.Name] || skip.covers(destField.Name) {
+			continue
+		}
+		if _, ignored := fieldName(destField, tagKeys); ignored {
+			continue
+		}
+		promoted := false
+		for i := 1; i < len(destField.Index); i++ {
+			if matched[destType.FieldByIndex(destField.Index[:i]).Name] {
+				promoted = true
+				break
+			}
+		}
+		if !promoted {
+			fields = append(fields, destField)
+		}
+	}
+	return fields
+}
+
+// mappable reports whether mapField is able to copy a value of srcType into a
+// value of destType.
+func mappable(srcType reflect.Type, destType reflect.Type) bool {
+	srcType, destType = indirectType(srcType), indirectType(destType)
+	if selfMappable(srcType, destType) {
+		return true
+	}
+	if destType.Kind() == reflect.Interface {
+		return srcType.Implements(destType)
+	}
+	if srcType == destType {
+		return true
+	}
+	if srcType.Kind() == reflect.Slice && destType.Kind() == reflect.Slice {
+		return mappable(srcType.Elem(), destType.Elem())
+	}
+	return srcType.Kind() == reflect.Struct && destType.Kind() == reflect.Struct
+}
+
+// mapField copies a single source value into a settable destination value,
+// following pointers, nested structs and slices on both sides. A nil source
+// pointer leaves the destination untouched.
+func (r *run) mapField(srcFieldValue reflect.Value, destFieldValue reflect.Value, skip skipSet) error {
+	srcFieldType := srcFieldValue.Type()
+	destFieldType := destFieldValue.Type()
 
-		if srcFieldType == destFieldType || destFieldType.Kind() == reflect.Interface {
-			if srcFieldValue.Kind() == reflect.Ptr && srcFieldValue.IsNil() {
-				if srcFieldValue.IsNil() {
-					continue
+	if srcFieldType.Kind() == reflect.Ptr {
+		srcFieldType = srcFieldType.Elem()
+	}
+
+	if destFieldType.Kind() == reflect.Ptr {
+		destFieldType = destFieldType.Elem()
+	}
+
+	if srcFieldValue.Kind() == reflect.Ptr && srcFieldValue.IsNil() {
+		return nil
+	}
+	if handled, err := r.mapSelf(srcFieldValue, destFieldValue); handled || err != nil {
+		return err
+	}
+
+	if destFieldType.Kind() == reflect.Interface {
+		return assignValue(destFieldValue, srcFieldValue)
+	} else if srcFieldType == destFieldType {
+		if srcFieldType.Kind() == reflect.Struct {
+			newDestValue := reflect.New(destFieldType)
+			if err := r.mapFields(reflect.Indirect(srcFieldValue), newDestValue.Elem(), skip); err != nil {
+				return err
+			}
+			if destFieldValue.Kind() == reflect.Ptr {
+				if destFieldValue.IsNil() {
+					destFieldValue.Set(newDestValue)
 				}
-				destFieldValue.Set(reflect.Zero(destFieldType))
+				destFieldValue = destFieldValue.Elem()
+				destFieldValue.Set(newDestValue.Elem())
+
 			} else {
-				if srcFieldType.Kind() == reflect.Struct {
-					newDestValue := reflect.New(destFieldType)
-					mapStruct(srcFieldValue.Interface(), newDestValue.Interface(), true)
-					//assignStructField(destFieldValue, newDestValue.Elem(), destFieldType)
-					if destFieldValue.Kind() == reflect.Ptr {
-						if destFieldValue.IsNil() {
-							destFieldValue.Set(newDestValue)
-						}
-						destFieldValue = destFieldValue.Elem()
-						destFieldValue.Set(newDestValue.Elem())
-
-					} else {
-						if newDestValue.Kind() == reflect.Ptr {
-							destFieldValue.Set(newDestValue.Elem())
-						} else {
-							destFieldValue.Set(newDestValue)
-						}
+				destFieldValue.Set(newDestValue.Elem())
+			}
+		} else if srcFieldType.Kind() == reflect.Slice {
+			srcSlice := reflect.Indirect(srcFieldValue)
 
+			destSlice := reflect.MakeSlice(destFieldType, srcSlice.Len(), srcSlice.Len())
+			for j := 0; j < srcSlice.Len(); j++ {
+				if srcSlice.Index(j).Type().Kind() == reflect.Struct {
+					newDestValue := reflect.New(destFieldType.Elem())
+					r.enter(fmt.Sprintf("[%d]", j))
+					err := r.mapStruct(srcSlice.Index(j).Interface(), newDestValue.Interface())
+					r.leave()
+					if err != nil {
+						return fmt.Errorf("index %d: %w", j, err)
 					}
-				} else if srcFieldType.Kind() == reflect.Slice {
-					srcSlice := srcFieldValue
-
-					destSlice := reflect.MakeSlice(destFieldType, srcSlice.Len(), srcSlice.Len())
-					for j := 0; j < srcSlice.Len(); j++ {
-						if srcSlice.Index(j).Type().Kind() == reflect.Struct {
-							newDestValue := reflect.New(destFieldType.Elem())
-							mapStruct(srcSlice.Index(j).Interface(), newDestValue.Interface(), false)
-							assignSliceElement(destSlice, newDestValue.Elem(), j)
-						} else {
-							assignSliceElement(destSlice, srcSlice.Index(j), j)
-						}
-					}
-					destFieldValue.Set(destSlice)
+					assignSliceElement(destSlice, newDestValue.Elem(), j)
 				} else {
-
-					assignValue(destFieldValue, srcFieldValue)
+					assignSliceElement(destSlice, srcSlice.Index(j), j)
 				}
 			}
+			if destFieldValue.Kind() == reflect.Ptr {
+				ptr := reflect.New(destFieldType)
+				ptr.Elem().Set(destSlice)
+				destFieldValue.Set(ptr)
+			} else {
+				destFieldValue.Set(destSlice)
+			}
+		} else if r.copyUnexported && (srcFieldType.Kind() == reflect.Map || srcFieldType.Kind() == reflect.Array) {
+			clone, err := r.cloneElements(reflect.Indirect(srcFieldValue))
+			if err != nil {
+				return err
+			}
+			if destFieldValue.Kind() == reflect.Ptr {
+				ptr := reflect.New(destFieldType)
+				ptr.Elem().Set(clone)
+				destFieldValue.Set(ptr)
+			} else {
+				destFieldValue.Set(clone)
+			}
 		} else {
-			if destFieldType.Kind() == reflect.Struct {
-				if destFieldValue.Kind() == reflect.Ptr {
-					newDestValue := reflect.New(destFieldType)
-					mapStruct(srcFieldValue.Interface(), newDestValue.Interface(), false)
-					destFieldValue.Set(newDestValue.Elem().Addr())
-				} else {
-					newDestValue := reflect.New(destFieldType)
-					mapStruct(srcFieldValue.Interface(), newDestValue.Interface(), false)
-					destFieldValue.Set(newDestValue.Elem())
-				}
+			return assignValue(destFieldValue, srcFieldValue)
+		}
+	} else if destFieldType.Kind() == reflect.Struct && srcFieldType.Kind() == reflect.Struct {
+		newDestValue := reflect.New(destFieldType)
+		if err := r.mapFields(reflect.Indirect(srcFieldValue), newDestValue.Elem(), skip); err != nil {
+			return err
+		}
+		if destFieldValue.Kind() == reflect.Ptr {
+			destFieldValue.Set(newDestValue)
+		} else {
+			destFieldValue.Set(newDestValue.Elem())
+		}
+	} else if destFieldType.Kind() == reflect.Slice && srcFieldType.Kind() == reflect.Slice {
+		srcSlice := reflect.Indirect(srcFieldValue)
 
+		destSlice := reflect.MakeSlice(destFieldType, srcSlice.Len(), srcSlice.Len())
+		for j := 0; j < srcSlice.Len(); j++ {
+			r.enter(fmt.Sprintf("[%d]", j))
+			err := r.mapField(srcSlice.Index(j), destSlice.Index(j), nil)
+			r.leave()
+			if err != nil {
+				return fmt.Errorf("index %d: %w", j, err)
 			}
 		}
-
+		if destFieldValue.Kind() == reflect.Ptr {
+			ptr := reflect.New(destFieldType)
+			ptr.Elem().Set(destSlice)
+			destFieldValue.Set(ptr)
+		} else {
+			destFieldValue.Set(destSlice)
+		}
 	}
+	return nil
 }
 
-func assignStructField(destFieldValue reflect.Value, newDestValue reflect.Value, fieldType reflect.Type) {
-	if destFieldValue.Kind() == reflect.Ptr {
-		newDestValue := reflect.New(fieldType)
-		if destFieldValue.IsNil() {
-			destFieldValue.Set(newDestValue)
+// cloneElements returns a copy of the map or array srcValue whose values are
+// mapped like slice elements, so that CopyUnexported does not share what
+// they point to with the source. Map keys are kept as they are.
+func (r *run) cloneElements(srcValue reflect.Value) (reflect.Value, error) {
+	if srcValue.Kind() == reflect.Map {
+		if srcValue.IsNil() {
+			return reflect.Zero(srcValue.Type()), nil
+		}
+		clone := reflect.MakeMapWithSize(srcValue.Type(), srcValue.Len())
+		elem := reflect.New(srcValue.Type().Elem()).Elem()
+		iter := srcValue.MapRange()
+		for iter.Next() {
+			elem.Set(reflect.Zero(elem.Type()))
+			r.enter(fmt.Sprintf("[%v]", iter.Key()))
+			err := r.mapField(iter.Value(), elem, nil)
+			r.leave()
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			clone.SetMapIndex(iter.Key(), elem)
+		}
+		return clone, nil
+	}
+	clone := reflect.New(srcValue.Type()).Elem()
+	for j := 0; j < srcValue.Len(); j++ {
+		r.enter(fmt.Sprintf("[%d]", j))
+		err := r.mapField(srcValue.Index(j), clone.Index(j), nil)
+		r.leave()
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("index %d: %w", j, err)
 		}
-		destFieldValue = destFieldValue.Elem()
-		destFieldValue.Set(newDestValue.Elem())
-	} else {
-		destFieldValue.Set(newDestValue)
 	}
+	return clone, nil
 }
 
 func assignSliceElement(destSlice reflect.Value, value reflect.Value, index int) {
-	if value.Type().Kind() == reflect.Ptr {
+	if value.Type().Kind() == reflect.Ptr && destSlice.Type().Elem().Kind() != reflect.Ptr {
 		destSlice.Index(index).Set(value.Elem())
 	} else {
 		destSlice.Index(index).Set(value)
 	}
 }
-func assignValue(destFieldValue reflect.Value, srcFieldValue reflect.Value) {
-	if destFieldValue.Kind() == reflect.Ptr {
-		if destFieldValue.Kind() == reflect.Ptr {
-			switch destFieldValue.Type().Elem().Kind() {
-			case reflect.String:
-				var str string
-				if srcFieldValue.Kind() == reflect.Ptr {
-					str = srcFieldValue.Elem().String()
-				} else {
-					str = srcFieldValue.String()
-				}
-
-				ptr := new(string)
-				*ptr = str
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Float32:
-				f := getFloat(srcFieldValue)
-				ptr := new(float32)
-				*ptr = float32(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Float64:
-				f := getFloat(srcFieldValue)
-				ptr := new(float64)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Uint:
-				f := getUint(srcFieldValue)
-				ptr := new(uint)
-				*ptr = uint(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Uint8:
-				f := getUint(srcFieldValue)
-				ptr := new(uint8)
-				*ptr = uint8(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Uint16:
-				f := getUint(srcFieldValue)
-				ptr := new(uint16)
-				*ptr = uint16(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Uint32:
-				f := getUint(srcFieldValue)
-				ptr := new(uint32)
-				*ptr = uint32(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Uint64:
-				f := getUint(srcFieldValue)
-				ptr := new(uint64)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Uintptr:
-				f := srcFieldValue.Interface().(uintptr)
-				ptr := new(uintptr)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Int:
-				i := getIntFromSrcValue(srcFieldValue)
-				ptr := new(int)
-				*ptr = int(i)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-
-			case reflect.Int16:
-				i := getIntFromSrcValue(srcFieldValue)
-				ptr := new(int16)
-				*ptr = int16(i)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Int32:
-				f := getIntFromSrcValue(srcFieldValue)
-				ptr := new(int32)
-				*ptr = int32(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Int64:
-				f := getIntFromSrcValue(srcFieldValue)
-				ptr := new(int64)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Complex64:
-				f := getComplex(srcFieldValue)
-				ptr := new(complex64)
-				*ptr = complex64(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Complex128:
-				f := getComplex(srcFieldValue)
-				ptr := new(complex128)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Map:
-				f := srcFieldValue.Interface().(map[string]interface{})
-				ptr := new(map[string]interface{})
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Int8:
-				f := getIntFromSrcValue(srcFieldValue)
-				ptr := new(int8)
-				*ptr = int8(f)
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			case reflect.Bool:
-				var f bool
-				if srcFieldValue.Kind() == reflect.Ptr {
-					f = srcFieldValue.Elem().Bool()
-				} else {
-					f = srcFieldValue.Bool()
-				}
-				ptr := new(bool)
-				*ptr = f
-				destFieldValue.Set(reflect.ValueOf(ptr))
-			// add more cases for other types
-			default:
-				assignValue(destFieldValue, srcFieldValue)
-			}
 
-		} else {
-			destFieldValue.Set(reflect.ValueOf(srcFieldValue.Interface()))
-		}
-	} else {
-		if srcFieldValue.Kind() == reflect.Ptr {
-			destFieldValue.Set(srcFieldValue.Elem())
-		} else {
-			destFieldValue.Set(srcFieldValue)
-		}
+// assignValue sets destFieldValue, of type T, *T, an interface or a pointer
+// to an interface, to srcFieldValue, of type T or a non-nil *T. Pointers are
+// set to a new copy of the value. Interfaces are set to the value
+// srcFieldValue points to if it implements them, or else to srcFieldValue.
+func assignValue(destFieldValue reflect.Value, srcFieldValue reflect.Value) error {
+	target := destFieldValue
+	if destFieldValue.Kind() == reflect.Ptr {
+		target = reflect.New(destFieldValue.Type().Elem()).Elem()
+	}
+	value := reflect.Indirect(srcFieldValue)
+	if !value.Type().AssignableTo(target.Type()) {
+		value = srcFieldValue
+	}
+	if !value.Type().AssignableTo(target.Type()) {
+		return fmt.Errorf("nilmapper: cannot assign %s to %s", srcFieldValue.Type(), destFieldValue.Type())
+	}
+	target.Set(value)
+	if destFieldValue.Kind() == reflect.Ptr {
+		destFieldValue.Set(target.Addr())
 	}
+	return nil
 }
@@ -32,7 +32,9 @@ func TestMapStruct(t *testing.T) {
 			FieldC: nil,
 		}
 		dest := DestStruct{}
-		mapStruct(src, &dest, false)
+		if err := Copy(src, &dest); err != nil {
+			t.Fatal(err)
+		}
 		if *dest.FieldA != "Test" || dest.FieldB != 123 || dest.FieldC != "" {
 			t.Errorf("Expected dest to be %+v, but got %+v", DestStruct{FieldA: &src.FieldA, FieldB: src.FieldB, FieldC: ""}, dest)
 		}
@@ -54,7 +56,9 @@ func TestMapStruct(t *testing.T) {
 			FieldA *string
 			FieldB DestStruct
 		}{}
-		mapStruct(src, &dest, true)
+		if err := Copy(src, &dest); err != nil {
+			t.Fatal(err)
+		}
 		if *dest.FieldA != "NestedTest" || *dest.FieldB.FieldA != "Test" || dest.FieldB.FieldB != 123 || dest.FieldB.FieldC != "" {
 			t.Errorf("Expected dest to be %+v, but got %+v", struct {
 				FieldA *string
@@ -115,7 +119,9 @@ func TestMapStructWithNested(t *testing.T) {
 		FieldC: &SourceNestedStruct{FieldD: "NestedTest"},
 	}
 	dest := DestStructWithNested{}
-	mapStruct(src, &dest, false)
+	if err := Copy(src, &dest); err != nil {
+		t.Fatal(err)
+	}
 	if dest.FieldC.FieldD != "NestedTest" {
 		t.Errorf("Expected dest to have FieldC.FieldD=%q, but got %q", "NestedTest", dest.FieldC.FieldD)
 	}
@@ -0,0 +1,107 @@
+package mappertest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/behrouz-rfa/nilmapper"
+	"github.com/go-playground/assert/v2"
+)
+
+type Address struct {
+	Street string
+	City   *string
+}
+
+type Line struct {
+	SKU   string
+	Count int
+}
+
+type Order struct {
+	ID      int
+	Name    *string
+	Notes   []string
+	Address *Address
+	Lines   []Line
+	Secret  string
+}
+
+type AddressDTO struct {
+	Street *string
+	City   string
+}
+
+type LineDTO struct {
+	SKU string
+}
+
+type OrderDTO struct {
+	Id      int
+	Name    string
+	Notes   []string
+	Address AddressDTO
+	Lines   []LineDTO
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	AssertRoundTrip[Order, OrderDTO](t)
+	AssertRoundTrip[Order, OrderDTO](t, Order{}, Order{Lines: []Line{{SKU: "a", Count: 2}}})
+}
+
+// recorder collects the errors reported by AssertRoundTrip.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRoundTripFailure(t *testing.T) {
+	m := nilmapper.New()
+	nilmapper.ConfigureOn[OrderDTO, Order](m).AfterMap(func(src OrderDTO, dst *Order) error {
+		if dst.Address != nil {
+			dst.Address.Street = strings.ToUpper(dst.Address.Street)
+		}
+		return nil
+	})
+
+	var rec recorder
+	AssertRoundTripOn[Order, OrderDTO](&rec, m,
+		Order{ID: 1, Address: &Address{Street: "Main"}, Lines: []Line{{SKU: "a", Count: 1}}},
+		Order{ID: 2, Address: &Address{Street: "MAIN"}},
+	)
+	assert.Equal(t, rec.errors, []string{`mappertest: sample 0: Address.Street: got "MAIN", want "Main"`})
+
+	rec.errors = nil
+	AssertRoundTrip[Order, struct{ Other int }](&rec)
+	assert.Equal(t, len(rec.errors), 1)
+}
+
+func TestRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var nilName, name, lines int
+	for i := 0; i < 200; i++ {
+		order := Random[Order](r)
+		if order.Name == nil {
+			nilName++
+		} else {
+			name++
+		}
+		lines += len(order.Lines)
+	}
+	if nilName == 0 || name == 0 || lines == 0 {
+		t.Errorf("expected nil and non-nil pointers and slices, got %d, %d and %d", nilName, name, lines)
+	}
+
+	type Tree struct {
+		Children []*Tree
+	}
+	_ = Random[Tree](r)
+}
@@ -0,0 +1,85 @@
+package mappertest
+
+import (
+	"math/rand"
+	"reflect"
+)
+
+// maxDepth is the depth below which Random leaves pointers, slices and maps
+// nil, so that recursive types are finite.
+const maxDepth = 4
+
+// Random returns a random T. Pointers, slices and maps are nil one time out
+// of four, slices and maps hold up to three elements, and nested structs are
+// filled in too. Unexported fields, interfaces, channels and functions are
+// left zero.
+func Random[T any](r *rand.Rand) T {
+	var v T
+	fill(r, reflect.ValueOf(&v).Elem(), 0)
+	return v
+}
+
+func fill(r *rand.Rand, v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Bool:
+		v.SetBool(r.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(r.Int63n(200) - 100)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(uint64(r.Int63n(200)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(r.Int63n(20000)-10000) / 100)
+	case reflect.Complex64, reflect.Complex128:
+		v.SetComplex(complex(float64(r.Int63n(200)-100), float64(r.Int63n(200)-100)))
+	case reflect.String:
+		v.SetString(randomString(r))
+	case reflect.Ptr:
+		if depth < maxDepth && r.Intn(4) != 0 {
+			ptr := reflect.New(v.Type().Elem())
+			fill(r, ptr.Elem(), depth+1)
+			v.Set(ptr)
+		}
+	case reflect.Slice:
+		if depth < maxDepth && r.Intn(4) != 0 {
+			n := r.Intn(4)
+			slice := reflect.MakeSlice(v.Type(), n, n)
+			for i := 0; i < n; i++ {
+				fill(r, slice.Index(i), depth+1)
+			}
+			v.Set(slice)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			fill(r, v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		if depth < maxDepth && r.Intn(4) != 0 {
+			n := r.Intn(4)
+			m := reflect.MakeMapWithSize(v.Type(), n)
+			for i := 0; i < n; i++ {
+				key := reflect.New(v.Type().Key()).Elem()
+				value := reflect.New(v.Type().Elem()).Elem()
+				fill(r, key, depth+1)
+				fill(r, value, depth+1)
+				m.SetMapIndex(key, value)
+			}
+			v.Set(m)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(r, v.Field(i), depth+1)
+			}
+		}
+	}
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+func randomString(r *rand.Rand) string {
+	b := make([]byte, r.Intn(8))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,240 @@
+// Package mappertest helps testing nilmapper mappings. AssertRoundTrip
+// checks that mapping a value into another type and back keeps every field
+// which is mapped both ways, and Random generates sample values:
+//
+//	func TestUserDTO(t *testing.T) {
+//		mappertest.AssertRoundTrip[User, UserDTO](t)
+//	}
+package mappertest
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/behrouz-rfa/nilmapper"
+)
+
+// Samples is the number of random values checked by AssertRoundTrip when it
+// is given no samples.
+const Samples = 100
+
+// AssertRoundTrip maps every sample from A into B and back into A with the
+// default Mapper, and reports an error on t for every field which is mapped
+// both ways but does not come back with the same value. Fields which are
+// mapped one way only, by a resolver or not at all, are not compared, and
+// nil pointers are considered equal to pointers to zero values. Without
+// samples, Samples random values are generated with a fixed seed.
+func AssertRoundTrip[A, B any](t testing.TB, samples ...A) {
+	t.Helper()
+	assertRoundTrip[A, B](t, nil, samples)
+}
+
+// AssertRoundTripOn is like AssertRoundTrip, but maps with m.
+func AssertRoundTripOn[A, B any](t testing.TB, m *nilmapper.Mapper, samples ...A) {
+	t.Helper()
+	assertRoundTrip[A, B](t, m, samples)
+}
+
+func assertRoundTrip[A, B any](t testing.TB, m *nilmapper.Mapper, samples []A) {
+	t.Helper()
+	typeA, typeB := reflect.TypeOf((*A)(nil)).Elem(), reflect.TypeOf((*B)(nil)).Elem()
+	plan, copyFn := nilmapper.PlanPair, nilmapper.Copy
+	if m != nil {
+		plan, copyFn = m.Plan, m.Copy
+	}
+	mask := roundTrips(plan(typeA, typeB), plan(typeB, typeA))
+	if mask == nil {
+		t.Errorf("mappertest: no field of %s is mapped to %s and back", typeA, typeB)
+		return
+	}
+
+	if len(samples) == 0 {
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < Samples; i++ {
+			samples = append(samples, Random[A](r))
+		}
+	}
+	for i, sample := range samples {
+		var b B
+		var back A
+		if err := copyFn(sample, &b); err != nil {
+			t.Errorf("mappertest: sample %d: %s to %s: %v", i, typeA, typeB, err)
+			continue
+		}
+		if err := copyFn(b, &back); err != nil {
+			t.Errorf("mappertest: sample %d: %s to %s: %v", i, typeB, typeA, err)
+			continue
+		}
+		compare("", reflect.ValueOf(sample), reflect.ValueOf(back), mask, func(path string, want, got reflect.Value) {
+			t.Errorf("mappertest: sample %d: %s: got %s, want %s", i, path, format(got), format(want))
+		})
+	}
+}
+
+// node is a tree of the field names of A which are compared. A leaf is
+// compared as a whole.
+type node struct {
+	leaf     bool
+	children map[string]*node
+	order    []string
+}
+
+func (n *node) add(path string) {
+	for _, name := range strings.Split(path, ".") {
+		name = strings.TrimSuffix(name, "[]")
+		if n.children == nil {
+			n.children = make(map[string]*node)
+		}
+		child, ok := n.children[name]
+		if !ok {
+			child = &node{}
+			n.children[name] = child
+			n.order = append(n.order, name)
+		}
+		n = child
+	}
+	n.leaf = true
+}
+
+// roundTrips returns the fields of A which forward maps into fields of B that
+// backward maps back into them, or nil if there are none.
+func roundTrips(forward *nilmapper.Plan, backward *nilmapper.Plan) *node {
+	there := make(map[string]string)
+	leaves("", "", forward.Fields, there)
+	back := make(map[string]string)
+	leaves("", "", backward.Fields, back)
+
+	srcs := make([]string, 0, len(there))
+	for src := range there {
+		srcs = append(srcs, src)
+	}
+	sort.Strings(srcs)
+	var mask *node
+	for _, src := range srcs {
+		if back[there[src]] == src {
+			if mask == nil {
+				mask = &node{}
+			}
+			mask.add(src)
+		}
+	}
+	return mask
+}
+
+// leaves adds the source and destination paths of the mapped leaf fields of
+// fields to paths. The elements of slices are marked with [].
+func leaves(src string, dest string, fields []nilmapper.FieldPlan, paths map[string]string) {
+	for _, field := range fields {
+		switch field.Match {
+		case nilmapper.MatchExact, nilmapper.MatchName, nilmapper.MatchTag, nilmapper.MatchPath:
+		default:
+			continue
+		}
+		if field.Conversion == nilmapper.ConvNone || len(field.Issues) > 0 {
+			continue
+		}
+		srcPath, destPath := join(src, field.Source), join(dest, field.Dest)
+		if len(field.Fields) == 0 {
+			paths[srcPath] = destPath
+			continue
+		}
+		if field.Conversion == nilmapper.ConvSlice || field.Conversion == nilmapper.ConvMerge {
+			srcPath, destPath = srcPath+"[]", destPath+"[]"
+		}
+		leaves(srcPath, destPath, field.Fields, paths)
+	}
+}
+
+func join(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// compare calls report for every field of n which differs between want and
+// got.
+func compare(path string, want reflect.Value, got reflect.Value, n *node, report func(path string, want, got reflect.Value)) {
+	want, got = deref(want), deref(got)
+	if n.leaf {
+		if !equal(want, got) {
+			report(path, want, got)
+		}
+		return
+	}
+	switch want.Kind() {
+	case reflect.Struct:
+		for _, name := range n.order {
+			compare(join(path, name), want.FieldByName(name), got.FieldByName(name), n.children[name], report)
+		}
+	case reflect.Slice, reflect.Array:
+		if want.Len() != got.Len() {
+			report(path, want, got)
+			return
+		}
+		for i := 0; i < want.Len(); i++ {
+			compare(fmt.Sprintf("%s[%d]", path, i), want.Index(i), got.Index(i), n, report)
+		}
+	}
+}
+
+// equal reports whether a and b are deeply equal, considering nil pointers
+// equal to pointers to zero values, and nil slices and maps equal to empty
+// ones. Unexported fields are not compared.
+func equal(a reflect.Value, b reflect.Value) bool {
+	a, b = deref(a), deref(b)
+	switch a.Kind() {
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if a.Type().Field(i).IsExported() && !equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			value := b.MapIndex(iter.Key())
+			if !value.IsValid() || !equal(iter.Value(), value) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// deref returns the value v points to, or the zero value for nil pointers.
+func deref(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Zero(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.Interface())
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
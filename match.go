@@ -0,0 +1,335 @@
+package nilmapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NameMatcher decides which destination field a source field is mapped
+// into when their names are not exactly the same. Key normalizes a field
+// name, and two fields match when their keys are equal.
+type NameMatcher interface {
+	Key(name string) string
+}
+
+// NameMatcherFunc adapts a function to a NameMatcher.
+type NameMatcherFunc func(name string) string
+
+// Key calls f(name).
+func (f NameMatcherFunc) Key(name string) string {
+	return f(name)
+}
+
+var (
+	// CaseInsensitive matches names which only differ in case, so that
+	// FieldID matches FieldId. It is the default.
+	CaseInsensitive NameMatcher = NameMatcherFunc(strings.ToLower)
+
+	// IgnoreUnderscores matches names which only differ in case and
+	// underscores, so that User_ID, UserID and UserId match.
+	IgnoreUnderscores NameMatcher = NameMatcherFunc(func(name string) string {
+		return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+	})
+
+	// ExactNames only matches names which are exactly the same.
+	ExactNames NameMatcher = NameMatcherFunc(func(name string) string {
+		return name
+	})
+)
+
+// WithNameMatcher sets the NameMatcher used when no destination field has
+// exactly the name of a source field. The matcher of a Mapper is also used
+// to resolve the paths of its configurations.
+func WithNameMatcher(matcher NameMatcher) Option {
+	return func(o *options) {
+		o.matcher = matcher
+	}
+}
+
+// MatchJSONTags matches source and destination fields by the names given to
+// them by their `json` struct tags, ignoring options such as omitempty.
+// Fields without a json tag keep their Go name, and fields tagged with
+// `json:"-"` are ignored on both sides. Fields which do not match by their
+// json names are then matched by their Go names. Configuration paths are
+// always made of Go names.
+func MatchJSONTags() Option {
+	return MatchTags([]string{"json"}, []string{"json"})
+}
+
+// MatchTags matches source fields by the names given to them by the first of
+// the struct tags of srcKeys they have, and destination fields by the first
+// of destKeys, so that existing tags drive the mapping:
+//
+//	// Map sqlx rows into API DTOs.
+//	nilmapper.MatchTags([]string{"db"}, []string{"json"})
+//
+// Like with MatchJSONTags, tag options are ignored, fields without any of the
+// tags keep their Go name, fields whose first tag is "-" are ignored, and
+// fields which do not match by their tag names are then matched by their Go
+// names. Either list may be empty to match that side by Go names.
+func MatchTags(srcKeys []string, destKeys []string) Option {
+	return func(o *options) {
+		o.srcTags, o.destTags = srcKeys, destKeys
+	}
+}
+
+// AmbiguousFieldError is returned by Copy when a source field does not
+// exactly match any destination field by name, but matches several of them
+// through the NameMatcher. It is also returned when several source fields
+// match the same destination field as closely, for example NAME and NaMe
+// both matching Name. Name is then the destination field, and Candidates and
+// Type are the source fields and their type.
+type AmbiguousFieldError struct {
+	Type       reflect.Type
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousFieldError) Error() string {
+	return fmt.Sprintf("nilmapper: %s matches %s of %s", e.Name, strings.Join(e.Candidates, " and "), e.Type)
+}
+
+// fieldIndex finds the fields of a struct type by name, first exactly and
+// then by the keys of a NameMatcher. The names are the Go names of the
+// fields, or the names given by the struct tags of tagKeys.
+type fieldIndex struct {
+	typ   reflect.Type
+	names map[string][]reflect.StructField
+	keys  map[string][]reflect.StructField
+	match NameMatcher
+	// exportedOnly leaves the unexported fields out, so that they neither
+	// match nor make matches ambiguous.
+	exportedOnly bool
+}
+
+func newFieldIndex(t reflect.Type, matcher NameMatcher, tagKeys []string, exportedOnly bool) *fieldIndex {
+	ix := &fieldIndex{typ: t, keys: make(map[string][]reflect.StructField), match: matcher, exportedOnly: exportedOnly}
+	if len(tagKeys) > 0 {
+		ix.names = make(map[string][]reflect.StructField)
+	}
+	for _, field := range reflect.VisibleFields(t) {
+		if exportedOnly && !field.IsExported() {
+			continue
+		}
+		name, ignored := fieldName(field, tagKeys)
+		if ignored {
+			continue
+		}
+		if ix.names != nil {
+			ix.names[name] = append(ix.names[name], field)
+		}
+		key := matcher.Key(name)
+		ix.keys[key] = append(ix.keys[key], field)
+	}
+	return ix
+}
+
+// lookup returns the field matching name. Like reflect.Type.FieldByName, it
+// prefers the least nested fields; if several of those match, an
+// *AmbiguousFieldError is returned.
+func (ix *fieldIndex) lookup(name string) (reflect.StructField, bool, error) {
+	if ix.names == nil {
+		if field, ok := ix.typ.FieldByName(name); ok && (field.IsExported() || !ix.exportedOnly) {
+			return field, true, nil
+		}
+	} else if candidates := leastNested(ix.names[name]); len(candidates) == 1 {
+		return candidates[0], true, nil
+	}
+	candidates := leastNested(ix.keys[ix.match.Key(name)])
+	switch len(candidates) {
+	case 0:
+		return reflect.StructField{}, false, nil
+	case 1:
+		return candidates[0], true, nil
+	}
+	names := make([]string, len(candidates))
+	for i, field := range candidates {
+		names[i] = field.Name
+	}
+	return reflect.StructField{}, false, &AmbiguousFieldError{Type: ix.typ, Name: name, Candidates: names}
+}
+
+// leastNested returns the fields of fields which are the least nested.
+func leastNested(fields []reflect.StructField) []reflect.StructField {
+	var candidates []reflect.StructField
+	for _, field := range fields {
+		switch {
+		case len(candidates) == 0 || len(field.Index) < len(candidates[0].Index):
+			candidates = append(candidates[:0], field)
+		case len(field.Index) == len(candidates[0].Index):
+			candidates = append(candidates, field)
+		}
+	}
+	return candidates
+}
+
+// fieldIndexes caches the field indexes of the struct types seen during a
+// single call.
+type fieldIndexes struct {
+	matcher NameMatcher
+	// srcTags and destTags are the keys of the struct tags naming the
+	// source and destination fields matched by match, if any.
+	srcTags  []string
+	destTags []string
+	// exportedOnly leaves the unexported fields out of the indexes, unless
+	// they are copied.
+	exportedOnly bool
+	indexes      map[reflect.Type]*fieldIndex
+	tagged       map[reflect.Type]*fieldIndex
+	// pairs are the matches of the fields of the struct pairs.
+	pairs map[typePair][]fieldMatch
+}
+
+// lookup returns the field of t with the Go name name.
+func (f *fieldIndexes) lookup(t reflect.Type, name string) (reflect.StructField, bool, error) {
+	return f.index(&f.indexes, t, nil).lookup(name)
+}
+
+func (f *fieldIndexes) index(indexes *map[reflect.Type]*fieldIndex, t reflect.Type, tagKeys []string) *fieldIndex {
+	ix, ok := (*indexes)[t]
+	if !ok {
+		if *indexes == nil {
+			*indexes = make(map[reflect.Type]*fieldIndex)
+		}
+		ix = newFieldIndex(t, f.matcher, tagKeys, f.exportedOnly)
+		(*indexes)[t] = ix
+	}
+	return ix
+}
+
+// fieldMatch is how a source field matches a destination field.
+type fieldMatch struct {
+	field reflect.StructField
+	match Match
+	err   error
+	// closeness orders the matches of a destination field: matching by the
+	// names given by struct tags beats matching by Go names, and exact names
+	// beat the NameMatcher.
+	closeness int
+	// shadowedBy is the name of the source field which matches field more
+	// closely, so that this one is not mapped.
+	shadowedBy string
+}
+
+// match returns the field of t which srcField is mapped into, and how it
+// matched. The match is "" if srcField does not match any field. Without tag
+// keys, fields match by their Go names. With tag keys, they first match by
+// the names given by their tags, and then by their Go names.
+func (f *fieldIndexes) match(t reflect.Type, srcField reflect.StructField) fieldMatch {
+	if len(f.srcTags) > 0 || len(f.destTags) > 0 {
+		name, ignored := fieldName(srcField, f.srcTags)
+		if ignored {
+			return fieldMatch{}
+		}
+		destField, ok, err := f.index(&f.tagged, t, f.destTags).lookup(name)
+		if err != nil {
+			return fieldMatch{err: err}
+		}
+		if ok {
+			destName, _ := fieldName(destField, f.destTags)
+			m := fieldMatch{field: destField, match: nameMatch(srcField, destField), closeness: 2}
+			if name != srcField.Name || destName != destField.Name {
+				m.match = MatchTag
+			}
+			if name == destName {
+				m.closeness++
+			}
+			return m
+		}
+	}
+	destField, ok, err := f.lookup(t, srcField.Name)
+	if !ok {
+		return fieldMatch{err: err}
+	}
+	if _, ignored := fieldName(destField, f.destTags); ignored {
+		return fieldMatch{}
+	}
+	m := fieldMatch{field: destField, match: nameMatch(srcField, destField)}
+	if m.match == MatchExact {
+		m.closeness++
+	}
+	return m
+}
+
+// matches returns how each field of srcType matches a field of destType, by
+// field index. When several source fields match the same destination field,
+// only the closest match is kept, and the other ones are shadowed by it. If
+// several are the closest, they are all ambiguous.
+func (f *fieldIndexes) matches(srcType reflect.Type, destType reflect.Type) []fieldMatch {
+	pair := typePair{src: srcType, dst: destType}
+	if ms, ok := f.pairs[pair]; ok {
+		return ms
+	}
+	ms := make([]fieldMatch, srcType.NumField())
+	for i := range ms {
+		if srcField := srcType.Field(i); srcField.IsExported() || !f.exportedOnly {
+			ms[i] = f.match(destType, srcField)
+		}
+	}
+	var group, closest []int
+	for i := range ms {
+		if ms[i].match == "" {
+			continue
+		}
+		group = append(group[:0], i)
+		for j := i + 1; j < len(ms); j++ {
+			if ms[j].match != "" && sameIndex(ms[i].field.Index, ms[j].field.Index) {
+				group = append(group, j)
+			}
+		}
+		if len(group) == 1 {
+			continue
+		}
+		closest = closest[:0]
+		for _, j := range group {
+			switch {
+			case len(closest) == 0 || ms[j].closeness > ms[closest[0]].closeness:
+				closest = append(closest[:0], j)
+			case ms[j].closeness == ms[closest[0]].closeness:
+				closest = append(closest, j)
+			}
+		}
+		if len(closest) == 1 {
+			for _, j := range group {
+				if j != closest[0] {
+					ms[j] = fieldMatch{shadowedBy: srcType.Field(closest[0]).Name}
+				}
+			}
+			continue
+		}
+		// Several source fields match as closely, so none of them wins.
+		names := make([]string, len(closest))
+		for k, j := range closest {
+			names[k] = srcType.Field(j).Name
+		}
+		err := &AmbiguousFieldError{Type: srcType, Name: ms[i].field.Name, Candidates: names}
+		for _, j := range group {
+			ms[j] = fieldMatch{err: err}
+		}
+	}
+	if f.pairs == nil {
+		f.pairs = make(map[typePair][]fieldMatch)
+	}
+	f.pairs[pair] = ms
+	return ms
+}
+
+func sameIndex(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func nameMatch(srcField reflect.StructField, destField reflect.StructField) Match {
+	if srcField.Name == destField.Name {
+		return MatchExact
+	}
+	return MatchName
+}
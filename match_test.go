@@ -0,0 +1,236 @@
+package nilmapper
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type IDSrc struct {
+	Id    int
+	Code  string
+	Label string
+}
+
+type IDDst struct {
+	Id   int
+	ID   int
+	CODE string
+	CoDe string
+}
+
+type SnakeSrc struct {
+	User_ID int
+	Name    string
+}
+
+type SnakeDst struct {
+	UserId int
+	NAME   string
+}
+
+func TestNameMatching(t *testing.T) {
+	t.Run("Exact First", func(t *testing.T) {
+		var dest IDDst
+		err := Copy(IDSrc{Id: 1}, &dest, WithNameMatcher(ExactNames))
+		assert.Equal(t, err, nil)
+		assert.Equal(t, dest, IDDst{Id: 1})
+	})
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		var dest IDDst
+		err := Copy(IDSrc{Id: 1, Code: "code"}, &dest)
+		var ambiguous *AmbiguousFieldError
+		if !errors.As(err, &ambiguous) {
+			t.Fatalf("expected an AmbiguousFieldError, got %v", err)
+		}
+		assert.Equal(t, ambiguous.Name, "Code")
+		assert.Equal(t, ambiguous.Candidates, []string{"CODE", "CoDe"})
+		assert.Equal(t, err.Error(), "nilmapper: Code matches CODE and CoDe of nilmapper.IDDst")
+	})
+
+	t.Run("Exact Source First", func(t *testing.T) {
+		type names struct{ Name, NAME string }
+		var dest struct{ Name string }
+		assert.Equal(t, Copy(names{Name: "a", NAME: "b"}, &dest), nil)
+		assert.Equal(t, dest.Name, "a")
+
+		err := ValidatePair(reflect.TypeOf(names{}), reflect.TypeOf(dest))
+		assert.Equal(t, err.(*ValidationError).Issues, []Issue{
+			{Kind: Shadowed, Path: "NAME", Message: "NAME matches a field of struct { Name string } which Name matches more closely"},
+		})
+	})
+
+	t.Run("Ambiguous Sources", func(t *testing.T) {
+		type names struct{ NAME, NaMe string }
+		var dest struct{ Name string }
+		err := Copy(names{NAME: "a", NaMe: "b"}, &dest)
+		assert.Equal(t, err.Error(), "nilmapper: Name matches NAME and NaMe of nilmapper.names")
+		assert.Equal(t, dest.Name, "")
+
+		err = ValidatePair(reflect.TypeOf(names{}), reflect.TypeOf(dest))
+		assert.Equal(t, len(err.(*ValidationError).Issues), 2)
+		assert.Equal(t, err.(*ValidationError).Issues[0], Issue{Kind: Ambiguous, Path: "NAME", Message: "Name matches NAME and NaMe of nilmapper.names"})
+	})
+
+	t.Run("Underscores", func(t *testing.T) {
+		var dest SnakeDst
+		assert.Equal(t, Copy(SnakeSrc{User_ID: 7, Name: "Ada"}, &dest), nil)
+		assert.Equal(t, dest, SnakeDst{NAME: "Ada"})
+
+		m := New(WithNameMatcher(IgnoreUnderscores))
+		assert.Equal(t, m.Copy(SnakeSrc{User_ID: 7, Name: "Ada"}, &dest), nil)
+		assert.Equal(t, dest, SnakeDst{UserId: 7, NAME: "Ada"})
+	})
+
+	t.Run("Custom", func(t *testing.T) {
+		trimID := NameMatcherFunc(func(name string) string {
+			return strings.TrimSuffix(strings.ToLower(name), "id")
+		})
+		var dest struct{ Owner string }
+		assert.Equal(t, Copy(struct{ OwnerID string }{OwnerID: "42"}, &dest, WithNameMatcher(trimID)), nil)
+		assert.Equal(t, dest.Owner, "42")
+	})
+
+	t.Run("Config", func(t *testing.T) {
+		m := New(WithNameMatcher(IgnoreUnderscores))
+		assert.Equal(t, ConfigureOn[SnakeSrc, SnakeDst](m).Field("user_id", "user_id").Err(), nil)
+		err := ConfigureOn[IDSrc, IDDst](New()).Field("code", "Code").Err()
+		assert.Equal(t, strings.Contains(err.Error(), `path "code": nilmapper: code matches CODE and CoDe`), true)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		err := ValidatePair(reflect.TypeOf(IDSrc{}), reflect.TypeOf(IDDst{}))
+		assert.Equal(t, err.(*ValidationError).Issues, []Issue{
+			{Kind: UnmatchedDestination, Path: "ID", Message: "no source field"},
+			{Kind: Ambiguous, Path: "Code", Message: "Code matches CODE and CoDe of nilmapper.IDDst"},
+		})
+		assert.Equal(t, ValidatePair(reflect.TypeOf(IDSrc{}), reflect.TypeOf(IDDst{}), WithNameMatcher(ExactNames), AllowUnmappedDestination()), nil)
+	})
+}
+
+type WireUser struct {
+	UserID   int    `json:"user_id"`
+	FullName string `json:"name,omitempty"`
+	Password string `json:"-"`
+	Email    string
+	Internal string `json:"-"`
+}
+
+type WireUserDTO struct {
+	ID          int    `json:"user_id"`
+	DisplayName string `json:"name"`
+	Password    string
+	Email       string `json:"email"`
+	Internal    string `json:"-"`
+}
+
+func TestMatchJSONTags(t *testing.T) {
+	src := WireUser{UserID: 7, FullName: "Ada", Password: "secret", Email: "ada@example.com", Internal: "internal"}
+	var dest WireUserDTO
+	assert.Equal(t, Copy(src, &dest), nil)
+	assert.Equal(t, dest, WireUserDTO{Password: "secret", Email: "ada@example.com", Internal: "internal"})
+
+	dest = WireUserDTO{}
+	assert.Equal(t, Copy(src, &dest, MatchJSONTags()), nil)
+	assert.Equal(t, dest, WireUserDTO{ID: 7, DisplayName: "Ada", Email: "ada@example.com"})
+
+	err := Copy(src, &dest, MatchJSONTags(), RequireAllDestinationFields())
+	assert.Equal(t, err.Error(), "nilmapper: unmatched destination fields: Password")
+
+	m := New(MatchJSONTags())
+	var matches []string
+	for _, field := range m.Plan(reflect.TypeOf(WireUser{}), reflect.TypeOf(WireUserDTO{})).Fields {
+		matches = append(matches, field.Dest+" "+string(field.Match))
+	}
+	assert.Equal(t, matches, []string{"ID tag", "DisplayName tag", "Password unmapped", "Email tag", "Internal ignored"})
+
+	// Fields whose json names do not match fall back to their Go names.
+	var fallback struct {
+		UserID int `json:"id"`
+	}
+	assert.Equal(t, m.Copy(src, &fallback), nil)
+	assert.Equal(t, fallback.UserID, 7)
+}
+
+type TaggedRow struct {
+	UserID int `json:"id"`
+	ID     int
+}
+
+type TaggedDTO struct {
+	ID int `json:"id"`
+}
+
+func TestMatchTagsShadowing(t *testing.T) {
+	// UserID claims ID by its tag, so ID does not overwrite it, in either
+	// order.
+	var dest TaggedDTO
+	assert.Equal(t, Copy(TaggedRow{UserID: 7, ID: 99}, &dest, MatchJSONTags()), nil)
+	assert.Equal(t, dest.ID, 7)
+
+	var reversed struct {
+		ID     int
+		UserID int `json:"id"`
+	}
+	reversed.ID, reversed.UserID = 99, 7
+	dest = TaggedDTO{}
+	assert.Equal(t, Copy(reversed, &dest, MatchJSONTags()), nil)
+	assert.Equal(t, dest.ID, 7)
+
+	err := ValidatePair(reflect.TypeOf(TaggedRow{}), reflect.TypeOf(TaggedDTO{}), MatchJSONTags())
+	assert.Equal(t, err.Error(), "nilmapper: mapping nilmapper.TaggedRow to nilmapper.TaggedDTO:\n"+
+		"\tID: shadowed: ID matches a field of nilmapper.TaggedDTO which UserID matches more closely")
+}
+
+type UserRow struct {
+	ID        int     `db:"user_id"`
+	Name      string  `db:"full_name" json:"name"`
+	Email     *string `db:"email"`
+	CreatedAt string  `db:"-" json:"createdAt"`
+	Country   string  `json:"country"`
+}
+
+type UserResponse struct {
+	UserID  int    `json:"user_id" yaml:"id"`
+	Display string `json:"full_name"`
+	Mail    string `yaml:"email" json:"mail"`
+	Created string `json:"createdAt"`
+	Country string `json:"-"`
+}
+
+func TestMatchTags(t *testing.T) {
+	email := "ada@example.com"
+	row := UserRow{ID: 7, Name: "Ada", Email: &email, CreatedAt: "today", Country: "UK"}
+	var resp UserResponse
+	assert.Equal(t, Copy(row, &resp, MatchTags([]string{"db"}, []string{"json"})), nil)
+	assert.Equal(t, resp, UserResponse{UserID: 7, Display: "Ada"})
+
+	// The first tag a field has wins, in the order of the keys.
+	resp = UserResponse{}
+	assert.Equal(t, Copy(row, &resp, MatchTags([]string{"db"}, []string{"yaml", "json"})), nil)
+	assert.Equal(t, resp, UserResponse{Display: "Ada", Mail: email})
+
+	resp = UserResponse{}
+	assert.Equal(t, Copy(row, &resp, MatchTags([]string{"json", "db"}, []string{"json"})), nil)
+	assert.Equal(t, resp, UserResponse{UserID: 7, Created: "today"})
+
+	// Without destination keys, source tag names match Go names.
+	var plain struct {
+		User_ID   int
+		Full_Name string
+	}
+	assert.Equal(t, Copy(row, &plain, MatchTags([]string{"db"}, nil), WithNameMatcher(IgnoreUnderscores)), nil)
+	assert.Equal(t, plain.User_ID, 7)
+	assert.Equal(t, plain.Full_Name, "Ada")
+
+	err := ValidatePair(reflect.TypeOf(UserRow{}), reflect.TypeOf(UserResponse{}), MatchTags([]string{"db"}, []string{"json"}), RequireAllDestinationFields())
+	var paths []string
+	for _, issue := range err.(*ValidationError).Issues {
+		paths = append(paths, issue.Path+" "+issue.Kind.String())
+	}
+	assert.Equal(t, paths, []string{"Mail unmatched destination", "Created unmatched destination"})
+}
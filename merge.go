@@ -0,0 +1,197 @@
+package nilmapper
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// mergeSpec makes a destination slice field merge the source elements into
+// the destination elements with the same key, instead of being replaced. It
+// is set with the mergekey tag option or with Config.MergeBy.
+type mergeSpec struct {
+	key string
+	// prune deletes the destination elements without a source element.
+	prune bool
+}
+
+// MergeBy makes the slice field of Dst at dstPath merge the source elements
+// into the destination elements with the same key field, for example the
+// items of an order updated by a PATCH payload. Matched elements are mapped
+// into the existing ones, unmatched source elements are appended, and
+// unmatched destination elements are kept, or deleted if prune is true. The
+// same can be declared with a struct tag:
+//
+//	Items []Item `nilmapper:",mergekey=ID,prune"`
+func (c *Config[Src, Dst]) MergeBy(dstPath string, key string, prune bool) *Config[Src, Dst] {
+	c.tm.mergeBy(dstPath, key, prune)
+	return c
+}
+
+func (tm *typeMap) mergeBy(dstPath string, key string, prune bool) {
+	dst, err := tm.resolvePath(tm.dst, dstPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	if dst.typ().Kind() != reflect.Slice {
+		tm.fail(fmt.Errorf("merge key for %s: %s is not a slice", dst, dst.typ()))
+		return
+	}
+	if len(dst) != 1 {
+		tm.fail(fmt.Errorf("merge key for %s: only fields of %s can be merged, configure the nested types instead", dst, tm.dst))
+		return
+	}
+	if _, err := mergeKey(&tm.fields, dst.typ(), key); err != nil {
+		tm.fail(fmt.Errorf("merge key for %s: %w", dst, err))
+		return
+	}
+	if tm.merges == nil {
+		tm.merges = make(map[string]mergeSpec)
+	}
+	tm.merges[dst[0].Name] = mergeSpec{key: key, prune: prune}
+}
+
+// mergeSpecOf returns how destField is merged, or nil if it is replaced.
+func mergeSpecOf(tm *typeMap, destField reflect.StructField) *mergeSpec {
+	if tm != nil {
+		if spec, ok := tm.merges[destField.Name]; ok {
+			return &spec
+		}
+	}
+	if destField.Type.Kind() != reflect.Slice {
+		return nil
+	}
+	if tag := parseTag(destField); tag.mergeKey != "" {
+		return &mergeSpec{key: tag.mergeKey, prune: tag.prune}
+	}
+	return nil
+}
+
+// mergeKey returns the key field of the elements of sliceType, which must be
+// structs or pointers to structs.
+func mergeKey(fields *fieldIndexes, sliceType reflect.Type, key string) (reflect.StructField, error) {
+	sliceType = indirectType(sliceType)
+	if sliceType.Kind() != reflect.Slice || indirectType(sliceType.Elem()).Kind() != reflect.Struct {
+		return reflect.StructField{}, fmt.Errorf("%s is not a slice of structs", sliceType)
+	}
+	elem := indirectType(sliceType.Elem())
+	field, ok, err := fields.lookup(elem, key)
+	if err != nil {
+		return reflect.StructField{}, err
+	}
+	if !ok || !field.IsExported() {
+		return reflect.StructField{}, fmt.Errorf("no field %q in %s", key, elem)
+	}
+	if !field.Type.Comparable() {
+		return reflect.StructField{}, fmt.Errorf("key %s of %s is not comparable", field.Name, elem)
+	}
+	return field, nil
+}
+
+// mergeKeys returns the key fields of the elements of a source and a
+// destination slice merged by key.
+func mergeKeys(fields *fieldIndexes, srcType reflect.Type, destType reflect.Type, key string) (reflect.StructField, reflect.StructField, error) {
+	srcKey, err := mergeKey(fields, srcType, key)
+	if err != nil {
+		return srcKey, srcKey, err
+	}
+	destKey, err := mergeKey(fields, destType, key)
+	if err != nil {
+		return srcKey, destKey, err
+	}
+	if srcKey.Type.Kind() != destKey.Type.Kind() || !srcKey.Type.ConvertibleTo(destKey.Type) {
+		return srcKey, destKey, fmt.Errorf("cannot compare key %s with %s", srcKey.Type, destKey.Type)
+	}
+	return srcKey, destKey, nil
+}
+
+var errNilKey = errors.New("nil element")
+
+// elemKey returns the key of a slice element.
+func elemKey(elem reflect.Value, key reflect.StructField, keyType reflect.Type) (interface{}, error) {
+	elem = reflect.Indirect(elem)
+	if !elem.IsValid() {
+		return nil, errNilKey
+	}
+	v, err := elem.FieldByIndexErr(key.Index)
+	if err != nil {
+		return nil, err
+	}
+	return v.Convert(keyType).Interface(), nil
+}
+
+// mergeSlice merges the elements of srcValue into the elements of destValue
+// with the same key.
+func (r *run) mergeSlice(srcValue reflect.Value, destValue reflect.Value, spec *mergeSpec) error {
+	if srcValue.Kind() == reflect.Ptr && srcValue.IsNil() {
+		return nil
+	}
+	srcKey, destKey, err := mergeKeys(&r.fields, srcValue.Type(), destValue.Type(), spec.key)
+	if err != nil {
+		return fmt.Errorf("merge key: %w", err)
+	}
+	srcSlice := reflect.Indirect(srcValue)
+	destType := destValue.Type()
+	destElemType := destType.Elem()
+
+	result := reflect.MakeSlice(destType, destValue.Len(), destValue.Len()+srcSlice.Len())
+	reflect.Copy(result, destValue)
+	positions := make(map[interface{}]int, destValue.Len())
+	for i := 0; i < destValue.Len(); i++ {
+		k, err := elemKey(destValue.Index(i), destKey, destKey.Type)
+		if err == errNilKey {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("index %d: %w", i, err)
+		}
+		if _, ok := positions[k]; !ok {
+			positions[k] = i
+		}
+	}
+
+	merged := make([]bool, destValue.Len())
+	for j := 0; j < srcSlice.Len(); j++ {
+		srcElem := srcSlice.Index(j)
+		k, err := elemKey(srcElem, srcKey, destKey.Type)
+		if err == errNilKey {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("index %d: %w", j, err)
+		}
+		destElem := reflect.New(destElemType).Elem()
+		i, ok := positions[k]
+		if ok {
+			destElem.Set(result.Index(i))
+			merged[i] = true
+		}
+		if destElem.Kind() == reflect.Ptr && destElem.IsNil() {
+			destElem.Set(reflect.New(destElemType.Elem()))
+		}
+		r.enter(fmt.Sprintf("[%d]", j))
+		err = r.mapFields(reflect.Indirect(srcElem), reflect.Indirect(destElem), nil)
+		r.leave()
+		if err != nil {
+			return fmt.Errorf("index %d: %w", j, err)
+		}
+		if ok {
+			result.Index(i).Set(destElem)
+		} else {
+			positions[k] = result.Len()
+			merged = append(merged, true)
+			result = reflect.Append(result, destElem)
+		}
+	}
+
+	if spec.prune {
+		kept := result.Slice(0, 0)
+		for i := 0; i < result.Len(); i++ {
+			if merged[i] {
+				kept = reflect.Append(kept, result.Index(i))
+			}
+		}
+		result = kept
+	}
+	destValue.Set(result)
+	return nil
+}
@@ -0,0 +1,107 @@
+package nilmapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type PatchItem struct {
+	ID       int
+	Quantity int
+}
+
+type PatchOrder struct {
+	Items []PatchItem
+}
+
+type StoredItem struct {
+	ID       int
+	Quantity int
+	Name     string
+}
+
+type StoredOrder struct {
+	Items []StoredItem `nilmapper:",mergekey=ID"`
+}
+
+type PrunedOrder struct {
+	Items []*StoredItem `nilmapper:",mergekey=ID,prune"`
+}
+
+func TestMergeKey(t *testing.T) {
+	patch := PatchOrder{Items: []PatchItem{{ID: 2, Quantity: 5}, {ID: 3, Quantity: 1}}}
+
+	t.Run("Tag", func(t *testing.T) {
+		dest := StoredOrder{Items: []StoredItem{{ID: 1, Quantity: 1, Name: "one"}, {ID: 2, Quantity: 1, Name: "two"}}}
+		assert.Equal(t, Copy(patch, &dest), nil)
+		assert.Equal(t, dest.Items, []StoredItem{
+			{ID: 1, Quantity: 1, Name: "one"},
+			{ID: 2, Quantity: 5, Name: "two"},
+			{ID: 3, Quantity: 1},
+		})
+	})
+
+	t.Run("Prune", func(t *testing.T) {
+		two := &StoredItem{ID: 2, Name: "two"}
+		dest := PrunedOrder{Items: []*StoredItem{{ID: 1, Name: "one"}, two, nil}}
+		assert.Equal(t, Copy(patch, &dest), nil)
+		assert.Equal(t, dest.Items, []*StoredItem{{ID: 2, Quantity: 5, Name: "two"}, {ID: 3, Quantity: 1}})
+		assert.Equal(t, dest.Items[0] == two, true)
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		plan := defaultMapper.Plan(typeOf[PatchOrder](), typeOf[StoredOrder]())
+		assert.Equal(t, plan.Fields[0].Conversion, ConvMerge)
+		// The names of the stored items are kept.
+		assert.Equal(t, ValidatePair(typeOf[PatchOrder](), typeOf[StoredOrder](), AllowUnmappedDestination()), nil)
+	})
+}
+
+type OrderLine struct {
+	SKU   string
+	Count int
+}
+
+type OrderLineDTO struct {
+	SKU   string
+	Count int
+	Note  string
+}
+
+type LinesSrc struct {
+	Lines []OrderLine
+	Tags  []string
+}
+
+type LinesDst struct {
+	Lines []OrderLineDTO
+	Tags  []string
+}
+
+func TestConfigureMergeBy(t *testing.T) {
+	m := New()
+	assert.Equal(t, ConfigureOn[LinesSrc, LinesDst](m).MergeBy("Lines", "SKU", false).Err(), nil)
+
+	dest := LinesDst{Lines: []OrderLineDTO{{SKU: "a", Count: 1, Note: "gift"}}}
+	assert.Equal(t, m.Copy(LinesSrc{Lines: []OrderLine{{SKU: "a", Count: 2}}}, &dest), nil)
+	assert.Equal(t, dest.Lines, []OrderLineDTO{{SKU: "a", Count: 2, Note: "gift"}})
+
+	// The default mapper replaces the slice.
+	assert.Equal(t, Copy(LinesSrc{Lines: []OrderLine{{SKU: "b"}}}, &dest), nil)
+	assert.Equal(t, dest.Lines, []OrderLineDTO{{SKU: "b"}})
+
+	err := ConfigureOn[LinesSrc, LinesDst](New()).
+		MergeBy("Lines", "Missing", false).
+		MergeBy("Tags", "ID", true).
+		Err()
+	if err == nil {
+		t.Fatal("expected a configuration error")
+	}
+	for _, want := range []string{`no field "Missing"`, "is not a slice of structs"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %q", want, err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package nilmapper
+
+import (
+	"strings"
+	"unicode"
+)
+
+var (
+	// SnakeCase matches names by their snake_case form, so that UserID,
+	// UserId, userId and user_id all match.
+	SnakeCase NameMatcher = NameMatcherFunc(func(name string) string {
+		return joinWords(splitWords(name, false), "_", strings.ToLower)
+	})
+
+	// CamelCase matches names by their camelCase words, so that UserID,
+	// UserId and userId match. Unlike SnakeCase, underscores are kept, so
+	// that user_id only matches names such as User_ID, and unlike
+	// CaseInsensitive, words must start at the same places, so that Userid
+	// does not match UserID.
+	CamelCase NameMatcher = NameMatcherFunc(func(name string) string {
+		parts := strings.Split(name, "_")
+		for i, part := range parts {
+			parts[i] = camelCase(splitWords(part, false))
+		}
+		return strings.Join(parts, "_")
+	})
+
+	// InitialismAware is like SnakeCase, but also splits runs of capitals made
+	// of common initialisms, so that HTTPURL matches HttpUrl and http_url.
+	InitialismAware NameMatcher = NameMatcherFunc(func(name string) string {
+		return joinWords(splitWords(name, true), "_", strings.ToLower)
+	})
+)
+
+// initialisms are the common initialisms recognized by InitialismAware.
+var initialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true,
+	"IP": true, "JSON": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true,
+	"RPC": true, "SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true,
+	"TLS": true, "TTL": true, "UDP": true, "UI": true, "UID": true, "UUID": true,
+	"URI": true, "URL": true, "UTF8": true, "VM": true, "XML": true, "XMPP": true,
+	"XSRF": true, "XSS": true,
+}
+
+// camelCase joins words in camelCase.
+func camelCase(words []string) string {
+	for i, word := range words {
+		word = strings.ToLower(word)
+		if i > 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+		words[i] = word
+	}
+	return strings.Join(words, "")
+}
+
+func joinWords(words []string, sep string, f func(string) string) string {
+	for i, word := range words {
+		words[i] = f(word)
+	}
+	return strings.Join(words, sep)
+}
+
+// splitWords splits a Go, camelCase or snake_case name into its words. A
+// run of capitals is a single word, unless splitInitialisms is set and the
+// run is made of initialisms only:
+//
+//	HTTPServer -> HTTP Server
+//	user_id    -> user id
+//	HTTPURL    -> HTTPURL, or HTTP URL when splitting initialisms
+func splitWords(name string, splitInitialisms bool) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			word := string(runes[start:end])
+			if splitInitialisms {
+				if parts := splitInitialismRun(word); parts != nil {
+					words = append(words, parts...)
+					start = end
+					return
+				}
+			}
+			words = append(words, word)
+		}
+		start = end
+	}
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-' || r == ' ':
+			flush(i)
+			start = i + 1
+		case i > start && unicode.IsUpper(r):
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			// Split userId before I, UTF8Name before N and HTTPServer
+			// before S.
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush(i)
+			}
+		}
+	}
+	flush(len(runes))
+	return words
+}
+
+// splitInitialismRun splits a run of capitals into initialisms, or returns
+// nil if it cannot be split entirely.
+func splitInitialismRun(word string) []string {
+	if len(word) < 2 || strings.ToUpper(word) != word || initialisms[word] {
+		return nil
+	}
+	for i := len(word) - 1; i > 0; i-- {
+		if !initialisms[word[:i]] {
+			continue
+		}
+		rest := word[i:]
+		if initialisms[rest] {
+			return []string{word[:i], rest}
+		}
+		if parts := splitInitialismRun(rest); parts != nil {
+			return append([]string{word[:i]}, parts...)
+		}
+	}
+	return nil
+}
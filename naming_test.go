@@ -0,0 +1,82 @@
+package nilmapper
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestSplitWords(t *testing.T) {
+	for name, want := range map[string][]string{
+		"UserID":     {"User", "ID"},
+		"userId":     {"user", "Id"},
+		"user_id":    {"user", "id"},
+		"HTTPServer": {"HTTP", "Server"},
+		"HTTPURL":    {"HTTPURL"},
+		"HttpUrl":    {"Http", "Url"},
+		"UTF8Name":   {"UTF8", "Name"},
+		"Address2":   {"Address2"},
+		"ID":         {"ID"},
+		"_private":   {"private"},
+	} {
+		assert.Equal(t, splitWords(name, false), want)
+	}
+
+	assert.Equal(t, splitWords("HTTPURL", true), []string{"HTTP", "URL"})
+	assert.Equal(t, splitWords("APIURLID", true), []string{"API", "URL", "ID"})
+	assert.Equal(t, splitWords("HTTPSXYZ", true), []string{"HTTPSXYZ"})
+	assert.Equal(t, splitWords("HTTPS", true), []string{"HTTPS"})
+}
+
+func TestNamingStrategies(t *testing.T) {
+	assert.Equal(t, SnakeCase.Key("UserID"), "user_id")
+	assert.Equal(t, SnakeCase.Key("user_id"), "user_id")
+	assert.Equal(t, CamelCase.Key("UserID"), "userId")
+	assert.Equal(t, CamelCase.Key("userId"), "userId")
+	assert.Equal(t, CamelCase.Key("user_id"), "user_id")
+	assert.Equal(t, CamelCase.Key("User_ID"), "user_id")
+	assert.Equal(t, CamelCase.Key("Userid") == CamelCase.Key("UserID"), false)
+	assert.Equal(t, InitialismAware.Key("HTTPURL"), "http_url")
+	assert.Equal(t, InitialismAware.Key("HttpUrl"), "http_url")
+	assert.Equal(t, SnakeCase.Key("HTTPURL") == SnakeCase.Key("HttpUrl"), false)
+}
+
+type ProtoUser struct {
+	UserId      int64
+	DisplayName string
+	HttpUrl     string
+}
+
+type RowUser struct {
+	User_id      int64
+	Display_name string
+	HTTPURL      string
+}
+
+func TestNamingStrategyMapper(t *testing.T) {
+	src := ProtoUser{UserId: 1, DisplayName: "Ada", HttpUrl: "https://example.com"}
+
+	var row RowUser
+	assert.Equal(t, New(WithNameMatcher(SnakeCase)).Copy(src, &row), nil)
+	assert.Equal(t, row, RowUser{User_id: 1, Display_name: "Ada"})
+
+	row = RowUser{}
+	m := New(WithNameMatcher(InitialismAware))
+	assert.Equal(t, m.Copy(src, &row), nil)
+	assert.Equal(t, row, RowUser{User_id: 1, Display_name: "Ada", HTTPURL: "https://example.com"})
+
+	var back ProtoUser
+	assert.Equal(t, m.Copy(row, &back), nil)
+	assert.Equal(t, back, src)
+
+	// CamelCase bridges Go and protobuf style names, but not snake_case.
+	var goUser struct {
+		UserID      int64
+		DisplayName string
+		User_id     int64
+	}
+	assert.Equal(t, New(WithNameMatcher(CamelCase)).Copy(src, &goUser), nil)
+	assert.Equal(t, goUser.UserID, int64(1))
+	assert.Equal(t, goUser.DisplayName, "Ada")
+	assert.Equal(t, goUser.User_id, int64(0))
+}
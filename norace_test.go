@@ -0,0 +1,5 @@
+//go:build !race
+
+package nilmapper
+
+const raceEnabled = false
@@ -0,0 +1,89 @@
+package nilmapper
+
+// Option configures a Mapper when it is passed to New, or a single call when
+// it is passed to Copy or CopySlice.
+type Option func(*options)
+
+type options struct {
+	requireDestination bool
+	requireSource      bool
+	allowUnmapped      bool
+	matcher            NameMatcher
+	srcTags            []string
+	destTags           []string
+	copyUnexported     bool
+	parallelism        int
+	sliceMode          SliceMode
+	report             *Report
+	dryRun             bool
+}
+
+// RequireAllDestinationFields makes Copy fail with an UnmatchedFieldsError
+// if a destination field is not mapped from a source field, a configured
+// path or a resolver. Fields whose source field cannot be converted into
+// them, such as an int into a string, are not mapped either. Fields tagged
+// with `nilmapper:",optional"` and fields ignored with Config.Ignore are not
+// required.
+func RequireAllDestinationFields() Option {
+	return func(o *options) {
+		o.requireDestination = true
+	}
+}
+
+// AllowUnmappedDestination makes Validate leave out the destination fields
+// which are not mapped from any source, which it reports by default. Copy
+// leaves such fields unchanged unless RequireAllDestinationFields is given,
+// which takes precedence.
+func AllowUnmappedDestination() Option {
+	return func(o *options) {
+		o.allowUnmapped = true
+	}
+}
+
+// RequireAllSourceFields makes Copy fail with an UnmatchedFieldsError if a
+// source field has no destination field to be mapped into. Fields tagged with
+// `nilmapper:",optional"` are not required.
+func RequireAllSourceFields() Option {
+	return func(o *options) {
+		o.requireSource = true
+	}
+}
+
+// CopyUnexported makes Copy map unexported fields too, matching them by name
+// like exported ones. It is meant for copies of values whose state lives in
+// unexported fields, such as caches. Pointers, slices, maps and arrays are
+// copied along with the values they hold, so the copy shares no pointer with
+// the source, except for map keys and interfaces. The fields are read and written through package unsafe, bypassing
+// the protection the reflect package gives them, so the values should be of
+// types owned by the caller. Without this option unexported fields are
+// skipped on both sides.
+func CopyUnexported() Option {
+	return func(o *options) {
+		o.copyUnexported = true
+	}
+}
+
+// Parallelism makes CopySlice map the elements of the slice with up to n
+// goroutines, each mapping a consecutive range of indexes. The result is the
+// same as mapping sequentially: once an element fails, the goroutines stop
+// before the indexes above it, and only the error of the lowest failing index
+// is returned. Resolvers and hooks must then be safe for concurrent use. n
+// below 2 maps sequentially.
+func Parallelism(n int) Option {
+	return func(o *options) {
+		o.parallelism = n
+	}
+}
+
+func (o options) with(opts []Option) options {
+	o.apply(opts)
+	return o
+}
+
+// apply sets opts on o in place, which unlike with does not move a copy of
+// o to the heap.
+func (o *options) apply(opts []Option) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
@@ -0,0 +1,106 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type ParallelSrc struct {
+	ID      int
+	Name    *string
+	Address StrictAddress
+	Lines   []StrictAddress
+	Extra   string
+}
+
+type ParallelDst struct {
+	ID      int
+	Name    string
+	Address *StrictAddress
+	Lines   []StrictAddressDst
+	Label   string
+	Missing string
+}
+
+func parallelRows(n int) []ParallelSrc {
+	rows := make([]ParallelSrc, n)
+	for i := range rows {
+		rows[i] = ParallelSrc{
+			ID:      i,
+			Name:    ToValue(fmt.Sprint("row ", i)),
+			Address: StrictAddress{Street: fmt.Sprint("street ", i)},
+			Lines:   []StrictAddress{{Street: "a"}, {City: "b"}},
+		}
+	}
+	return rows
+}
+
+func TestParallelism(t *testing.T) {
+	m := New()
+	cfg := ConfigureOn[ParallelSrc, ParallelDst](m).
+		ForField("Label", func(src ParallelSrc) (string, error) {
+			if src.ID%250 == 7 {
+				return "", errors.New("rejected")
+			}
+			return fmt.Sprint("#", src.ID), nil
+		})
+	assert.Equal(t, cfg.Err(), nil)
+
+	t.Run("Same as sequential", func(t *testing.T) {
+		rows := parallelRows(1000)
+		for i := range rows {
+			rows[i].ID = i * 2
+		}
+		var want, got []ParallelDst
+		opts := []Option{RequireAllDestinationFields(), RequireAllSourceFields()}
+		wantErr := m.CopySlice(rows, &want, opts...)
+		gotErr := m.CopySlice(rows, &got, append(opts, Parallelism(7))...)
+		assert.Equal(t, gotErr, wantErr)
+		assert.Equal(t, got, want)
+
+		// The unmatched fields are reported once, like sequentially.
+		var unmatched *UnmatchedFieldsError
+		if !errors.As(gotErr, &unmatched) {
+			t.Fatalf("expected an UnmatchedFieldsError, got %v", gotErr)
+		}
+		assert.Equal(t, unmatched.Destination, []string{"Lines.Country", "Missing"})
+		assert.Equal(t, unmatched.Source, []string{"Lines.City", "Extra"})
+
+		got = nil
+		assert.Equal(t, m.CopySlice(rows, &got, Parallelism(4)), nil)
+		assert.Equal(t, len(got), 1000)
+		assert.Equal(t, got[999].Label, "#1998")
+		assert.Equal(t, got[999].Address.Street, "street 999")
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		rows := parallelRows(1000)
+		for _, n := range []int{2, 3, 8, 1000, 5000} {
+			var dest []ParallelDst
+			err := m.CopySlice(rows, &dest, Parallelism(n))
+			if err == nil {
+				t.Fatalf("Parallelism(%d): expected an error", n)
+			}
+			assert.Equal(t, err.Error(), "nilmapper: index 7: Label: rejected")
+			assert.Equal(t, dest, []ParallelDst(nil))
+		}
+
+		var dest []ParallelDst
+		assert.Equal(t, m.CopySlice(rows, &dest, Parallelism(2)), m.CopySlice(rows, &dest))
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var dest []ParallelDst
+		err := m.CopySliceContext(ctx, parallelRows(10), &dest, Parallelism(3))
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+		assert.Equal(t, err.Error(), "nilmapper: index 0: context canceled")
+	})
+}
@@ -0,0 +1,398 @@
+package nilmapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Match tells how a destination field is matched with its source.
+type Match string
+
+const (
+	// MatchExact is a source field with the same name.
+	MatchExact Match = "exact"
+	// MatchName is a source field whose name matches through the
+	// NameMatcher, for example in another case.
+	MatchName Match = "name matcher"
+	// MatchTag is a source field whose name given by a struct tag matches
+	// the one of the destination field, with MatchTags or MatchJSONTags.
+	MatchTag Match = "tag"
+	// MatchPath is a source path configured with Config.Field.
+	MatchPath Match = "path"
+	// MatchResolver is a resolver configured with Config.ForField.
+	MatchResolver Match = "resolver"
+	// MatchInverse is a field filled by an Inverse in the configuration
+	// built by Config.Reverse. Source is the path of the resolver undone.
+	MatchInverse Match = "inverse"
+	// MatchIgnored is a field ignored with Config.Ignore.
+	MatchIgnored Match = "ignored"
+	// MatchUnmapped is a destination field without a source.
+	MatchUnmapped Match = "unmapped"
+	// MatchUnmatched is a source field without a destination.
+	MatchUnmatched Match = "unmatched"
+	// MatchAmbiguous is a source field matching several destination fields.
+	MatchAmbiguous Match = "ambiguous"
+	// MatchShadowed is a source field matching a destination field which
+	// another source field matches more closely, so it is not mapped.
+	MatchShadowed Match = "shadowed"
+)
+
+// Conversion tells how a source value is turned into a destination value.
+type Conversion string
+
+const (
+	// ConvDirect sets the value as is.
+	ConvDirect Conversion = "direct"
+	// ConvPointerWrap sets a pointer to a copy of the value.
+	ConvPointerWrap Conversion = "pointer wrap"
+	// ConvDeref sets the value the source pointer points to.
+	ConvDeref Conversion = "deref"
+	// ConvPointerCopy sets a pointer to a copy of the value the source
+	// pointer points to.
+	ConvPointerCopy Conversion = "pointer copy"
+	// ConvNestedStruct maps a struct field by field.
+	ConvNestedStruct Conversion = "nested struct"
+	// ConvSlice maps a slice element by element.
+	ConvSlice Conversion = "slice"
+	// ConvMerge merges a slice into the destination elements with the same
+	// key.
+	ConvMerge Conversion = "keyed merge"
+	// ConvSelf leaves the mapping to MapTo or MapFrom.
+	ConvSelf Conversion = "self"
+	// ConvInterface stores the value in an interface.
+	ConvInterface Conversion = "interface"
+	// ConvNone means that no conversion exists, so the field is skipped.
+	ConvNone Conversion = "none"
+)
+
+// Plan describes how Copy maps a source type into a destination type.
+type Plan struct {
+	Src        string      `json:"src"`
+	Dst        string      `json:"dst"`
+	Conversion Conversion  `json:"conversion"`
+	Fields     []FieldPlan `json:"fields,omitempty"`
+}
+
+// FieldPlan describes how a single field is mapped. Dest is empty for
+// source fields which have no destination, and Source is empty for
+// destination fields which have none. Dest and Source are paths relative to
+// the enclosing struct. Fields holds the plan of a nested struct or of the
+// struct elements of a slice.
+type FieldPlan struct {
+	Dest       string      `json:"dest,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Match      Match       `json:"match"`
+	Conversion Conversion  `json:"conversion,omitempty"`
+	Optional   bool        `json:"optional,omitempty"`
+	Recursive  bool        `json:"recursive,omitempty"`
+	Fields     []FieldPlan `json:"fields,omitempty"`
+	Issues     []Issue     `json:"issues,omitempty"`
+}
+
+// Explain describes how the default Mapper maps Src into Dst: for every
+// destination field which source field feeds it, how the two are matched
+// and converted, and which fields are not mapped at all. It is meant to be
+// printed while debugging a mapping:
+//
+//	fmt.Println(nilmapper.Explain[User, UserDTO]())
+func Explain[Src, Dst any]() string {
+	return defaultMapper.Plan(typeOf[Src](), typeOf[Dst]()).String()
+}
+
+// ExplainJSON is like Explain, but returns the plan encoded as JSON.
+func ExplainJSON[Src, Dst any]() ([]byte, error) {
+	return json.Marshal(defaultMapper.Plan(typeOf[Src](), typeOf[Dst]()))
+}
+
+// PlanPair returns how the default Mapper maps srcType into destType.
+func PlanPair(srcType reflect.Type, destType reflect.Type) *Plan {
+	return defaultMapper.Plan(srcType, destType)
+}
+
+// Plan returns how m maps srcType into destType.
+func (m *Mapper) Plan(srcType reflect.Type, destType reflect.Type) *Plan {
+	return m.plan(srcType, destType, m.opts)
+}
+
+func (m *Mapper) plan(srcType reflect.Type, destType reflect.Type, o options) *Plan {
+	p := &planner{Mapper: m, visiting: make(map[typePair]bool)}
+	p.fields.matcher, p.fields.srcTags, p.fields.destTags = o.matcher, o.srcTags, o.destTags
+	p.unexported = o.copyUnexported
+	p.fields.exportedOnly = !o.copyUnexported
+	p.all.matcher, p.all.srcTags, p.all.destTags = o.matcher, o.srcTags, o.destTags
+	plan := &Plan{Src: srcType.String(), Dst: destType.String()}
+	if selfMappable(srcType, destType) {
+		plan.Conversion = ConvSelf
+		return plan
+	}
+	plan.Conversion = ConvNestedStruct
+	srcType, destType = indirectType(srcType), indirectType(destType)
+	if srcType.Kind() != reflect.Struct || destType.Kind() != reflect.Struct {
+		plan.Conversion = ConvNone
+		return plan
+	}
+	plan.Fields, _ = p.structs(srcType, destType, nil)
+	return plan
+}
+
+// String formats the plan as a table, with the fields of nested structs
+// indented below their parent.
+func (p *Plan) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DESTINATION\tSOURCE\tMATCH\tCONVERSION\tISSUES")
+	writeFieldPlans(w, p.Fields, "")
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return fmt.Sprintf("%s -> %s (%s)\n%s\n", p.Src, p.Dst, p.Conversion, strings.Join(lines, "\n"))
+}
+
+func writeFieldPlans(w *tabwriter.Writer, fields []FieldPlan, indent string) {
+	for _, field := range fields {
+		conversion := string(field.Conversion)
+		if field.Recursive {
+			conversion += " (recursive)"
+		}
+		var issues []string
+		for _, issue := range field.Issues {
+			issues = append(issues, fmt.Sprintf("%s: %s", issue.Kind, issue.Message))
+		}
+		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n", indent, field.Dest, field.Source, field.Match, conversion, strings.Join(issues, "; "))
+		writeFieldPlans(w, field.Fields, indent+"  ")
+	}
+}
+
+// planner walks a pair of types the way mapFields and mapField walk a pair
+// of values.
+type planner struct {
+	*Mapper
+	// visiting holds the pairs of structs being planned, to stop at
+	// recursive types.
+	visiting map[typePair]bool
+	fields   fieldIndexes
+	// all also indexes the unexported fields, to report the source fields
+	// which only match one of them.
+	all        fieldIndexes
+	unexported bool
+}
+
+// structs mirrors mapFields. It reports false if the pair is already being
+// planned further up.
+func (p *planner) structs(srcType reflect.Type, destType reflect.Type, skip skipSet) ([]FieldPlan, bool) {
+	pair := typePair{src: srcType, dst: destType}
+	if p.visiting[pair] {
+		return nil, false
+	}
+	p.visiting[pair] = true
+	defer delete(p.visiting, pair)
+
+	tm := p.lookupTypeMap(srcType, destType)
+	if tm != nil {
+		if tm.err != nil {
+			return []FieldPlan{{Match: MatchUnmapped, Conversion: ConvNone, Issues: []Issue{{Kind: InvalidConfig, Message: tm.err.Error()}}}}, true
+		}
+		skip = append(skip, tm.skip)
+	}
+
+	type source struct {
+		field reflect.StructField
+		match Match
+	}
+	sources := make(map[string]source)
+	matched := make(map[string]bool)
+	var unmatched []FieldPlan
+	matches := p.fields.matches(srcType, destType)
+	for i := 0; i < srcType.NumField(); i++ {
+		srcField := srcType.Field(i)
+		name := srcField.Name
+		if !srcField.IsExported() && !p.unexported {
+			continue
+		}
+		if _, ignored := fieldName(srcField, p.fields.srcTags); ignored {
+			continue
+		}
+		m := matches[i]
+		if m.match == "" && m.err == nil && m.shadowedBy == "" && !p.unexported {
+			if hidden := p.all.match(destType, srcField); !hidden.field.IsExported() {
+				m = hidden
+			}
+		}
+		destField, match := m.field, m.match
+		if match == "" {
+			if tm != nil && tm.used[name] {
+				continue
+			}
+			if m.shadowedBy != "" {
+				unmatched = append(unmatched, FieldPlan{Source: name, Match: MatchShadowed, Issues: []Issue{{
+					Kind:    Shadowed,
+					Message: fmt.Sprintf("%s matches a field of %s which %s matches more closely", name, destType, m.shadowedBy),
+				}}})
+			} else if ambiguous, isAmbiguous := m.err.(*AmbiguousFieldError); isAmbiguous {
+				// The ambiguous destination fields are reported as such, not
+				// as unmapped.
+				if ambiguous.Type == destType {
+					for _, candidate := range ambiguous.Candidates {
+						matched[candidate] = true
+					}
+				} else {
+					matched[ambiguous.Name] = true
+				}
+				unmatched = append(unmatched, FieldPlan{Source: name, Match: MatchAmbiguous, Issues: []Issue{{
+					Kind:    Ambiguous,
+					Message: fmt.Sprintf("%s matches %s of %s", ambiguous.Name, strings.Join(ambiguous.Candidates, " and "), ambiguous.Type),
+				}}})
+			} else if srcField.IsExported() {
+				unmatched = append(unmatched, FieldPlan{Source: name, Match: MatchUnmatched, Optional: parseTag(srcField).optional})
+			}
+			continue
+		}
+		matched[destField.Name] = true
+		sources[destField.Name] = source{field: srcField, match: match}
+	}
+
+	var fields []FieldPlan
+	for _, destField := range reflect.VisibleFields(destType) {
+		name := destField.Name
+		src, ok := sources[name]
+		if !ok {
+			if _, ignored := fieldName(destField, p.fields.destTags); ignored || skip.ignores(name) {
+				fields = append(fields, FieldPlan{Dest: name, Match: MatchIgnored})
+			}
+			continue
+		}
+		field := FieldPlan{Dest: name, Source: src.field.Name, Match: src.match}
+		switch {
+		case skip.ignores(name):
+			field = FieldPlan{Dest: name, Match: MatchIgnored}
+		case skip.has(name):
+			continue
+		case !destField.IsExported() && !p.unexported:
+			field.Conversion = ConvNone
+			field.Issues = []Issue{{Kind: Skipped, Message: "destination field is not exported"}}
+		default:
+			p.value(&field, src.field.Type, destField.Type, skip.child(name))
+			if spec := mergeSpecOf(tm, destField); spec != nil {
+				field.Conversion = ConvMerge
+				if _, _, err := mergeKeys(&p.fields, src.field.Type, destField.Type, spec.key); err != nil {
+					field.Issues = append(field.Issues, Issue{Kind: InvalidConfig, Message: "merge key: " + err.Error()})
+				}
+			}
+		}
+		fields = append(fields, field)
+	}
+	for _, destField := range unmatchedFields(destType, matched, skip, p.fields.destTags) {
+		fields = append(fields, FieldPlan{Dest: destField.Name, Match: MatchUnmapped, Optional: parseTag(destField).optional})
+	}
+
+	if tm != nil {
+		for _, rule := range tm.rules {
+			field := FieldPlan{Dest: rule.dst.String(), Source: rule.src.String(), Match: MatchPath}
+			var srcFieldType reflect.Type
+			if rule.resolver.IsValid() {
+				field.Source = rule.resolver.Type().String()
+				field.Match = MatchResolver
+				srcFieldType = rule.resolver.Type().Out(0)
+			} else {
+				srcFieldType = rule.src.typ()
+			}
+			p.value(&field, srcFieldType, rule.dst.typ(), nil)
+			fields = append(fields, field)
+		}
+		for _, filled := range tm.filled {
+			fields = append(fields, FieldPlan{Dest: filled.dst.String(), Source: filled.by, Match: MatchInverse})
+		}
+	}
+
+	// Keep the destination fields, including the ones assigned by rules, in
+	// the order in which they are declared.
+	order := make(map[string]int)
+	for i, destField := range reflect.VisibleFields(destType) {
+		order[destField.Name] = i
+	}
+	sort.SliceStable(fields, func(i, j int) bool {
+		return order[strings.SplitN(fields[i].Dest, ".", 2)[0]] < order[strings.SplitN(fields[j].Dest, ".", 2)[0]]
+	})
+	if tm != nil {
+		for _, path := range tm.irreversible {
+			unmatched = append(unmatched, FieldPlan{Source: path, Match: MatchResolver, Conversion: ConvNone, Issues: []Issue{{
+				Kind:    Irreversible,
+				Message: "the resolver computing it has no inverse",
+			}}})
+		}
+	}
+	return append(fields, unmatched...), true
+}
+
+// value mirrors mapField and fills in the conversion, nested fields and
+// issues of field.
+func (p *planner) value(field *FieldPlan, srcType reflect.Type, destType reflect.Type, skip skipSet) {
+	issue := func(kind IssueKind, format string, args ...interface{}) {
+		field.Issues = append(field.Issues, Issue{Kind: kind, Message: fmt.Sprintf(format, args...)})
+	}
+	nested := func(srcType reflect.Type, destType reflect.Type, skip skipSet) {
+		var ok bool
+		field.Fields, ok = p.structs(srcType, destType, skip)
+		field.Recursive = !ok
+	}
+
+	if selfMappable(srcType, destType) {
+		field.Conversion = ConvSelf
+		return
+	}
+	srcElem, destElem := indirectType(srcType), indirectType(destType)
+	switch {
+	case destElem.Kind() == reflect.Interface:
+		field.Conversion = ConvInterface
+		if !srcType.AssignableTo(destElem) && !srcElem.AssignableTo(destElem) {
+			issue(Fails, "%s does not implement %s", srcElem, destElem)
+		}
+	case srcElem == destElem:
+		switch srcElem.Kind() {
+		case reflect.Struct:
+			field.Conversion = ConvNestedStruct
+			nested(srcElem, destElem, skip)
+		case reflect.Slice:
+			field.Conversion = ConvSlice
+			if elem := srcElem.Elem(); elem.Kind() == reflect.Struct && !selfMappable(elem, elem) {
+				nested(elem, elem, nil)
+			}
+		default:
+			field.Conversion = pointerConversion(srcType, destType)
+		}
+	case srcElem.Kind() == reflect.Struct && destElem.Kind() == reflect.Struct:
+		field.Conversion = ConvNestedStruct
+		nested(srcElem, destElem, skip)
+	case srcElem.Kind() == reflect.Slice && destElem.Kind() == reflect.Slice:
+		field.Conversion = ConvSlice
+		elem := FieldPlan{}
+		p.value(&elem, srcElem.Elem(), destElem.Elem(), nil)
+		field.Fields, field.Recursive = elem.Fields, elem.Recursive
+		field.Issues = append(field.Issues, elem.Issues...)
+		if elem.Conversion == ConvNone {
+			field.Conversion = ConvNone
+		}
+	default:
+		field.Conversion = ConvNone
+		issue(NoConversion, "cannot map %s into %s", srcType, destType)
+	}
+}
+
+func pointerConversion(srcType reflect.Type, destType reflect.Type) Conversion {
+	switch srcPtr, destPtr := srcType.Kind() == reflect.Ptr, destType.Kind() == reflect.Ptr; {
+	case srcPtr && destPtr:
+		return ConvPointerCopy
+	case srcPtr:
+		return ConvDeref
+	case destPtr:
+		return ConvPointerWrap
+	}
+	return ConvDirect
+}
@@ -0,0 +1,89 @@
+package nilmapper
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type PlanSrc struct {
+	Name     string
+	UserId   int
+	Address  *Address
+	Lines    []Address
+	Internal string
+	Extra    string
+}
+
+type PlanDst struct {
+	Name     *string
+	UserID   int
+	Address  Address2
+	Lines    []*Address2
+	City     string
+	Internal string
+	Label    string
+}
+
+func TestExplain(t *testing.T) {
+	m := New()
+	ConfigureOn[PlanSrc, PlanDst](m).
+		Field("City", "Address.Address").
+		ForField("Label", func(src PlanSrc) string { return src.Name }).
+		Ignore("Internal")
+
+	plan := m.Plan(typeOf[PlanSrc](), typeOf[PlanDst]())
+	assert.Equal(t, plan.String(), `nilmapper.PlanSrc -> nilmapper.PlanDst (nested struct)
+DESTINATION  SOURCE                          MATCH         CONVERSION     ISSUES
+Name         Name                            exact         pointer wrap
+UserID       UserId                          name matcher  direct
+Address      Address                         exact         nested struct
+  Address    Address                         exact         direct
+  Code       Code                            exact         pointer copy
+Lines        Lines                           exact         slice
+  Address    Address                         exact         direct
+  Code       Code                            exact         pointer copy
+City         Address.Address                 path          direct
+Internal                                     ignored
+Label        func(nilmapper.PlanSrc) string  resolver      direct
+             Extra                           unmatched
+`)
+
+	data, err := json.Marshal(plan)
+	assert.Equal(t, err, nil)
+	var decoded Plan
+	assert.Equal(t, json.Unmarshal(data, &decoded), nil)
+	assert.Equal(t, decoded, *plan)
+}
+
+type Node struct {
+	Name     string
+	Children []Node
+	Next     *Node
+}
+
+type NodeDTO struct {
+	Name     string
+	Children []NodeDTO
+	Next     *NodeDTO
+	Lookup   map[string]int
+}
+
+func TestExplainRecursive(t *testing.T) {
+	assert.Equal(t, Explain[Node, NodeDTO](), `nilmapper.Node -> nilmapper.NodeDTO (nested struct)
+DESTINATION  SOURCE    MATCH     CONVERSION                 ISSUES
+Name         Name      exact     direct
+Children     Children  exact     slice (recursive)
+Next         Next      exact     nested struct (recursive)
+Lookup                 unmapped
+`)
+
+	data, err := ExplainJSON[Node, NodeDTO]()
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(data), `{"src":"nilmapper.Node","dst":"nilmapper.NodeDTO","conversion":"nested struct","fields":[`+
+		`{"dest":"Name","source":"Name","match":"exact","conversion":"direct"},`+
+		`{"dest":"Children","source":"Children","match":"exact","conversion":"slice","recursive":true},`+
+		`{"dest":"Next","source":"Next","match":"exact","conversion":"nested struct","recursive":true},`+
+		`{"dest":"Lookup","match":"unmapped"}]}`)
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package nilmapper
+
+// raceEnabled is set when the tests run with the race detector, which makes
+// allocation counts unreliable.
+const raceEnabled = true
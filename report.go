@@ -0,0 +1,117 @@
+package nilmapper
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Report lists what a Copy or CopySlice call did to the destination. The
+// paths are destination paths, for example Owner.Email, prefixed with the
+// index for the elements of CopySlice, as in [2].Owner.Email. Nested structs
+// are reported field by field, while slices and values mapped by MapTo or
+// MapFrom are reported as a whole.
+type Report struct {
+	// Written holds the fields which were assigned.
+	Written []string `json:"written"`
+	// SkippedNil holds the fields which were left untouched because their
+	// source was a nil pointer.
+	SkippedNil []string `json:"skippedNil"`
+	// Unmatched holds the fields without a source field, or whose source
+	// field cannot be converted into them.
+	Unmatched []string `json:"unmatched"`
+}
+
+// WithReport makes Copy and CopySlice fill report, which is reset at the
+// start of every call. Together with DryRun it tells which columns an update
+// would touch:
+//
+//	var report nilmapper.Report
+//	err := nilmapper.Copy(patch, &user, nilmapper.WithReport(&report), nilmapper.DryRun())
+func WithReport(report *Report) Option {
+	return func(o *options) {
+		o.report = report
+	}
+}
+
+// DryRun makes Copy and CopySlice map into a copy of the destination, so
+// that the destination is left untouched. Hooks and resolvers still run. It
+// is meant to be used with WithReport, or to check that a mapping succeeds.
+func DryRun() Option {
+	return func(o *options) {
+		o.dryRun = true
+	}
+}
+
+// target returns the value a call maps into: destination, or a copy of it
+// for DryRun.
+func (r *run) target(destination interface{}) interface{} {
+	if !r.dryRun {
+		return destination
+	}
+	dest := reflect.ValueOf(destination).Elem()
+	clone := reflect.New(dest.Type())
+	deepCopy(clone.Elem(), addressable(dest))
+	return clone.Interface()
+}
+
+// reportPath returns the path of the value being mapped, with name appended
+// if it is not empty.
+func (r *run) reportPath(name string) string {
+	var b strings.Builder
+	for _, segment := range r.path {
+		if b.Len() > 0 && !strings.HasPrefix(segment, "[") {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	if name != "" {
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(name)
+	}
+	return b.String()
+}
+
+// recording reports whether the fields mapped now are reported, which is not
+// the case inside a value reported as a whole.
+func (r *run) recording() bool {
+	return r.report != nil && r.quiet == 0
+}
+
+// beginField is called before the field at the end of r.path is mapped from
+// srcValue into destValue. It reports whether the field is reported as a
+// whole, in which case endField must be called once it is mapped.
+func (r *run) beginField(srcValue reflect.Value, destValue reflect.Value) bool {
+	if !r.recording() {
+		return false
+	}
+	if srcValue.Kind() == reflect.Ptr && srcValue.IsNil() {
+		r.report.SkippedNil = append(r.report.SkippedNil, r.reportPath(""))
+		return false
+	}
+	if nestedStruct(srcValue.Type(), destValue.Type()) {
+		return false
+	}
+	r.quiet++
+	return true
+}
+
+func (r *run) endField(whole bool, err error) {
+	if !whole {
+		return
+	}
+	r.quiet--
+	if err == nil {
+		r.report.Written = append(r.report.Written, r.reportPath(""))
+	}
+}
+
+// nestedStruct reports whether mapField maps srcType into destType field by
+// field.
+func nestedStruct(srcType reflect.Type, destType reflect.Type) bool {
+	if selfMappable(srcType, destType) {
+		return false
+	}
+	return indirectType(srcType).Kind() == reflect.Struct && indirectType(destType).Kind() == reflect.Struct
+}
@@ -0,0 +1,97 @@
+package nilmapper
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type UserPatch struct {
+	Name    *string
+	Email   *string
+	Address *StrictAddress
+	Tags    []string
+	Extra   string
+}
+
+type UserRecord struct {
+	Name    string
+	Email   string
+	Address StrictAddressDst
+	Tags    []string
+	Created string
+}
+
+func TestReport(t *testing.T) {
+	patch := UserPatch{
+		Name:    ToValue("Ada"),
+		Address: &StrictAddress{Street: "Main"},
+		Tags:    []string{"a"},
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		var report Report
+		dest := UserRecord{Email: "kept@example.com"}
+		assert.Equal(t, Copy(patch, &dest, WithReport(&report)), nil)
+		assert.Equal(t, report, Report{
+			Written:    []string{"Name", "Address.Street", "Tags"},
+			SkippedNil: []string{"Email"},
+			Unmatched:  []string{"Address.Country", "Created"},
+		})
+		assert.Equal(t, dest.Name, "Ada")
+		assert.Equal(t, dest.Email, "kept@example.com")
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		var report Report
+		dest := UserRecord{Name: "Old", Tags: []string{"old"}}
+		assert.Equal(t, Copy(patch, &dest, WithReport(&report), DryRun()), nil)
+		assert.Equal(t, report.Written, []string{"Name", "Address.Street", "Tags"})
+		assert.Equal(t, dest, UserRecord{Name: "Old", Tags: []string{"old"}})
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		var report Report
+		var dest []UserRecord
+		src := []UserPatch{patch, {Email: ToValue("b@example.com")}}
+		assert.Equal(t, CopySlice(src, &dest, WithReport(&report), Parallelism(2)), nil)
+		assert.Equal(t, report.Written, []string{"[0].Name", "[0].Address.Street", "[0].Tags", "[1].Email", "[1].Tags"})
+		assert.Equal(t, report.SkippedNil, []string{"[0].Email", "[1].Name", "[1].Address"})
+
+		var dry []UserRecord
+		assert.Equal(t, CopySlice(src, &dry, DryRun()), nil)
+		assert.Equal(t, dry, []UserRecord(nil))
+	})
+
+	t.Run("Rules", func(t *testing.T) {
+		m := New()
+		assert.Equal(t, ConfigureOn[OrderSrc, OrderDst](m).Field("Owner.Email", "User.Contact.Email").Ignore("Internal").Err(), nil)
+		var report Report
+		src := OrderSrc{Title: "Order", User: Account{Contact: &Contact{}}}
+		assert.Equal(t, m.Copy(src, &OrderDst{}, WithReport(&report)), nil)
+		assert.Equal(t, report.Written, []string{"Title"})
+		assert.Equal(t, report.SkippedNil, []string{"Owner.Email"})
+	})
+
+	t.Run("No conversion", func(t *testing.T) {
+		var report Report
+		var dest struct{ Name, Count string }
+		src := struct {
+			Name  string
+			Count int
+		}{Name: "Ada", Count: 3}
+		assert.Equal(t, Copy(src, &dest, WithReport(&report)), nil)
+		assert.Equal(t, report, Report{Written: []string{"Name"}, Unmatched: []string{"Count"}})
+	})
+
+	t.Run("Cyclic dry run", func(t *testing.T) {
+		root := &treeNode{Name: "root"}
+		child := &treeNode{Name: "a", Parent: root}
+		root.Children = []*treeNode{child}
+		var report Report
+		assert.Equal(t, Copy(struct{ Name string }{Name: "x"}, child, WithReport(&report), DryRun()), nil)
+		assert.Equal(t, report.Written, []string{"Name"})
+		assert.Equal(t, child.Name, "a")
+		assert.Equal(t, root.Children[0] == child, true)
+	})
+}
@@ -0,0 +1,152 @@
+package nilmapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Inverse gives the inverse of the resolver configured with ForField for
+// dstPath. It is used by the reverse configuration built by Reverse, and is
+// called with the Dst value once its fields have been mapped back into the
+// Src value. srcPaths are the Src fields the inverse fills in, which the
+// reverse configuration then neither maps by name nor reports as unmapped:
+//
+//	Configure[User, UserDTO]().
+//		ForField("FullName", func(src User) string {
+//			return src.First + " " + src.Last
+//		}).
+//		Inverse("FullName", func(dst UserDTO, src *User) error {
+//			src.First, src.Last, _ = strings.Cut(dst.FullName, " ")
+//			return nil
+//		}, "First", "Last").
+//		Reverse()
+func (c *Config[Src, Dst]) Inverse(dstPath string, inverse func(dst Dst, src *Src) error, srcPaths ...string) *Config[Src, Dst] {
+	c.tm.inverse(dstPath, func(srcValue reflect.Value, destValue reflect.Value) error {
+		return inverse(valueAs[Dst](srcValue), destValue.Addr().Interface().(*Src))
+	}, srcPaths)
+	return c
+}
+
+// OneWay marks the resolver configured with ForField for dstPath as having
+// no inverse on purpose, so that Validate does not report it for the reverse
+// configuration.
+func (c *Config[Src, Dst]) OneWay(dstPath string) *Config[Src, Dst] {
+	c.tm.inverse(dstPath, nil, nil)
+	return c
+}
+
+// Reverse registers and returns the configuration mapping Dst values back
+// into Src values, on the same Mapper. Field paths are swapped, so that
+// flattened fields are expanded again, fields ignored in Dst are ignored in
+// Src, and resolvers are replaced by their Inverse. Fields matched by name
+// need no configuration in either direction. Resolvers without an inverse
+// which are not marked OneWay are reported by Validate for the reverse pair.
+// Reverse must be called once the configuration is complete, and replaces any
+// configuration already registered for the reverse pair.
+func (c *Config[Src, Dst]) Reverse() *Config[Dst, Src] {
+	rev := ConfigureOn[Dst, Src](c.m)
+	rev.tm.reverseOf(c.tm)
+	return rev
+}
+
+// inverseRule is the inverse of a resolver, with the source paths it fills.
+// hook is nil for the resolvers marked OneWay.
+type inverseRule struct {
+	hook  hookFunc
+	fills []fieldPath
+}
+
+// filledPath is a destination path filled by the inverse of the resolver of
+// the forward configuration for the path by.
+type filledPath struct {
+	dst fieldPath
+	by  string
+}
+
+func (tm *typeMap) inverse(dstPath string, hook hookFunc, srcPaths []string) {
+	dst, err := tm.resolvePath(tm.dst, dstPath)
+	if err != nil {
+		tm.fail(err)
+		return
+	}
+	inverse := inverseRule{hook: hook}
+	for _, srcPath := range srcPaths {
+		src, err := tm.resolvePath(tm.src, srcPath)
+		if err != nil {
+			tm.fail(err)
+			return
+		}
+		inverse.fills = append(inverse.fills, src)
+	}
+	for _, rule := range tm.rules {
+		if rule.resolver.IsValid() && rule.dst.String() == dst.String() {
+			if tm.inverses == nil {
+				tm.inverses = make(map[string]inverseRule)
+			}
+			tm.inverses[dst.String()] = inverse
+			return
+		}
+	}
+	tm.fail(fmt.Errorf("no resolver is configured for %s", dst))
+}
+
+// reverseOf fills tm, which maps the destination type of fwd into its source
+// type, with the inverse of the rules of fwd.
+func (tm *typeMap) reverseOf(fwd *typeMap) {
+	if fwd.err != nil {
+		tm.fail(fmt.Errorf("reverse of an invalid configuration: %w", fwd.err))
+		return
+	}
+	for _, rule := range fwd.rules {
+		if !rule.resolver.IsValid() {
+			tm.field(rule.src.String(), rule.dst.String())
+			continue
+		}
+		inverse, ok := fwd.inverses[rule.dst.String()]
+		switch {
+		case !ok:
+			tm.irreversible = append(tm.irreversible, rule.dst.String())
+		case inverse.hook != nil:
+			// The inverse reads the field the resolver computed.
+			tm.after = append(tm.after, inverse.hook)
+			tm.used[rule.dst[0].Name] = true
+		}
+		for _, src := range inverse.fills {
+			if tm.claim(src, false) {
+				tm.filled = append(tm.filled, filledPath{dst: src, by: rule.dst.String()})
+			}
+		}
+	}
+	for _, dst := range fwd.ignored {
+		if src, ok := fwd.sourceOf(dst); ok {
+			tm.ignore(src)
+		}
+	}
+}
+
+// sourceOf returns the path of the source fields which are mapped by name
+// into the fields of the destination path dst, if any.
+func (tm *typeMap) sourceOf(dst fieldPath) (string, bool) {
+	names := make([]string, 0, len(dst))
+	srcType, destType := tm.src, tm.dst
+	for _, field := range dst {
+		if srcType.Kind() != reflect.Struct {
+			return "", false
+		}
+		var next reflect.Type
+		for i := 0; i < srcType.NumField(); i++ {
+			src := srcType.Field(i)
+			if match, ok, _ := tm.fields.lookup(destType, src.Name); ok && match.Name == field.Name {
+				names = append(names, src.Name)
+				next = indirectType(src.Type)
+				break
+			}
+		}
+		if next == nil {
+			return "", false
+		}
+		srcType, destType = next, indirectType(field.Type)
+	}
+	return strings.Join(names, "."), true
+}
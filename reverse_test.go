@@ -0,0 +1,123 @@
+package nilmapper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type Person struct {
+	First   string
+	Last    string
+	Age     int
+	User    Account
+	Secret  string
+	Address StrictAddress
+}
+
+type PersonDTO struct {
+	FullName   string
+	Initials   string
+	AGE        int
+	OwnerEmail *string
+	Secret     string
+	Address    StrictAddress
+}
+
+func configurePerson(m *Mapper) *Config[Person, PersonDTO] {
+	return ConfigureOn[Person, PersonDTO](m).
+		Field("OwnerEmail", "User.Contact.Email").
+		ForField("FullName", func(src Person) string {
+			return src.First + " " + src.Last
+		}).
+		ForField("Initials", func(src Person) string {
+			return src.First[:1] + src.Last[:1]
+		}).
+		Ignore("Secret").
+		Ignore("Address.City")
+}
+
+func TestReverse(t *testing.T) {
+	m := New()
+	rev := configurePerson(m).
+		Inverse("FullName", func(dst PersonDTO, src *Person) error {
+			first, last, ok := strings.Cut(dst.FullName, " ")
+			if !ok {
+				return errors.New("full name without a space")
+			}
+			src.First, src.Last = first, last
+			return nil
+		}, "First", "Last").
+		OneWay("Initials").
+		Reverse()
+	assert.Equal(t, rev.Err(), nil)
+
+	src := Person{
+		First:   "Ada",
+		Last:    "Lovelace",
+		Age:     36,
+		User:    Account{Contact: &Contact{Email: ToValue("ada@example.com")}},
+		Secret:  "secret",
+		Address: StrictAddress{Street: "Main", City: "London"},
+	}
+	var dto PersonDTO
+	assert.Equal(t, m.Copy(src, &dto), nil)
+	assert.Equal(t, dto.FullName, "Ada Lovelace")
+	assert.Equal(t, dto.Initials, "AL")
+	assert.Equal(t, dto.Address, StrictAddress{Street: "Main"})
+
+	dto.Secret = "leaked"
+	dto.Address.City = "Paris"
+	back := Person{Secret: "kept", Address: StrictAddress{City: "London"}}
+	assert.Equal(t, m.Copy(dto, &back), nil)
+	assert.Equal(t, back.First, "Ada")
+	assert.Equal(t, back.Last, "Lovelace")
+	assert.Equal(t, back.Age, 36)
+	assert.Equal(t, *back.User.Contact.Email, "ada@example.com")
+	assert.Equal(t, back.Secret, "kept")
+	// Nested structs are mapped into new values, so the ignored City is not
+	// copied back from the DTO.
+	assert.Equal(t, back.Address, StrictAddress{Street: "Main"})
+
+	assert.Equal(t, m.ValidatePair(typeOf[PersonDTO](), typeOf[Person]()), nil)
+	plan := m.Plan(typeOf[PersonDTO](), typeOf[Person]())
+	assert.Equal(t, plan.Fields[:2], []FieldPlan{
+		{Dest: "First", Source: "FullName", Match: MatchInverse},
+		{Dest: "Last", Source: "FullName", Match: MatchInverse},
+	})
+
+	dto.FullName = "Ada"
+	assert.Equal(t, m.Copy(dto, &back).Error(), "full name without a space")
+}
+
+func TestReverseIrreversible(t *testing.T) {
+	m := New()
+	fwd := configurePerson(m)
+	assert.Equal(t, fwd.Reverse().Err(), nil)
+
+	err := m.ValidatePair(typeOf[PersonDTO](), typeOf[Person]())
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	assert.Equal(t, validation.Issues, []Issue{
+		{Kind: UnmatchedDestination, Path: "First", Message: "no source field"},
+		{Kind: UnmatchedDestination, Path: "Last", Message: "no source field"},
+		{Kind: Irreversible, Path: "FullName", Message: "the resolver computing it has no inverse"},
+		{Kind: Irreversible, Path: "Initials", Message: "the resolver computing it has no inverse"},
+	})
+
+	err = ConfigureOn[Person, PersonDTO](m).Inverse("Secret", func(dst PersonDTO, src *Person) error { return nil }).Err()
+	if err == nil || !strings.Contains(err.Error(), "no resolver is configured for Secret") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	err = ConfigureOn[Person, PersonDTO](m).
+		ForField("FullName", func(src Person) string { return src.First }).
+		Inverse("FullName", func(dst PersonDTO, src *Person) error { return nil }, "Middle").Err()
+	if err == nil || !strings.Contains(err.Error(), "Middle") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
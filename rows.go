@@ -0,0 +1,261 @@
+package nilmapper
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// ToRows turns a slice of structs, or of pointers to structs, into rows of
+// values for SQL or CSV exports. Every exported field is a column, named
+// after its Go name or, with MatchTags or MatchJSONTags, after the source tag
+// naming it. Fields tagged "-", with the source tag or `nilmapper:"-"`, are
+// left out. Nested structs are flattened into columns named like
+// "Address.City", and the fields of embedded structs are columns of their
+// own, unless a column of the same name is declared less deeply, as with Go
+// selectors. Two columns of the same name at the same depth are an error.
+// Columns are in the order in which the fields are declared, except that
+// fields tagged with `nilmapper:",order=N"` come first, by increasing N.
+//
+// Nil pointers, including nil structs and nil elements, give nil values, which
+// database/sql writes as NULL. Other pointers are followed. Structs with no
+// exported fields, or implementing driver.Valuer or encoding.TextMarshaler,
+// such as time.Time, are single columns.
+func ToRows(source interface{}, opts ...Option) (header []string, rows [][]interface{}, err error) {
+	return defaultMapper.ToRows(source, opts...)
+}
+
+// ToRows is like the package level ToRows, but uses the options of m.
+func (m *Mapper) ToRows(source interface{}, opts ...Option) (header []string, rows [][]interface{}, err error) {
+	o := m.opts.with(opts)
+	srcValue := reflect.ValueOf(source)
+	if srcValue.Kind() == reflect.Ptr && !srcValue.IsNil() {
+		srcValue = srcValue.Elem()
+	}
+	if srcValue.Kind() != reflect.Slice || indirectType(srcValue.Type().Elem()).Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("nilmapper: ToRows needs a slice of structs, got %T", source)
+	}
+	columns, err := rowColumns(indirectType(srcValue.Type().Elem()), "", nil, 0, o.srcTags, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, nil, err
+	}
+	header = make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	rows = make([][]interface{}, srcValue.Len())
+	for i := range rows {
+		row := make([]interface{}, len(columns))
+		for j, column := range columns {
+			row[j] = column.value(srcValue.Index(i))
+		}
+		rows[i] = row
+	}
+	return header, rows, nil
+}
+
+// WriteCSV writes the header and rows built by ToRows to w as CSV. Nil values
+// are written as empty strings, values implementing encoding.TextMarshaler
+// or driver.Valuer as their text or value, and other values with fmt.Sprint.
+func WriteCSV(w io.Writer, source interface{}, opts ...Option) error {
+	return defaultMapper.WriteCSV(w, source, opts...)
+}
+
+// WriteCSV is like the package level WriteCSV, but uses the options of m.
+func (m *Mapper) WriteCSV(w io.Writer, source interface{}, opts ...Option) error {
+	header, rows, err := m.ToRows(source, opts...)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i, value := range row {
+			if record[i], err = csvField(value); err != nil {
+				return fmt.Errorf("nilmapper: column %s: %w", header[i], err)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvField(value interface{}) (string, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case encoding.TextMarshaler:
+		text, err := v.MarshalText()
+		return string(text), err
+	}
+	return fmt.Sprint(value), nil
+}
+
+// rowColumn is a column of ToRows, whose value is found by following the
+// field indexes of path from the element of the slice. depth is the number of
+// embedded structs the field is promoted through.
+type rowColumn struct {
+	name  string
+	path  []int
+	depth int
+}
+
+var (
+	valuerType        = typeOf[driver.Valuer]()
+	textMarshalerType = typeOf[encoding.TextMarshaler]()
+)
+
+// rowColumns returns the columns of the struct type t, whose names start with
+// prefix and whose paths start with path. depth is the number of embedded
+// structs t is reached through, whose columns are resolved by the caller
+// once depth is 0. visiting holds the struct types being flattened, to
+// reject recursive types.
+func rowColumns(t reflect.Type, prefix string, path []int, depth int, tagKeys []string, visiting map[reflect.Type]bool) ([]rowColumn, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("nilmapper: ToRows cannot flatten the recursive type %s", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	type group struct {
+		order   int
+		columns []rowColumn
+	}
+	var groups []group
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, ignored := fieldName(field, tagKeys)
+		tag := parseTag(field)
+		if ignored || tag.omit {
+			continue
+		}
+		fieldPath := append(path[:len(path):len(path)], i)
+		g := group{order: tag.order}
+		fieldType := indirectType(field.Type)
+		if fieldType.Kind() == reflect.Struct && !columnType(fieldType) {
+			var columns []rowColumn
+			var err error
+			if field.Anonymous {
+				columns, err = rowColumns(fieldType, prefix, fieldPath, depth+1, tagKeys, visiting)
+			} else {
+				columns, err = rowColumns(fieldType, prefix+name+".", fieldPath, 0, tagKeys, visiting)
+				for j := range columns {
+					columns[j].depth = depth
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			g.columns = columns
+		} else {
+			g.columns = []rowColumn{{name: prefix + name, path: fieldPath, depth: depth}}
+		}
+		groups = append(groups, g)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i].order, groups[j].order
+		return a > 0 && (b <= 0 || a < b)
+	})
+	var columns []rowColumn
+	for _, g := range groups {
+		columns = append(columns, g.columns...)
+	}
+	if depth > 0 {
+		return columns, nil
+	}
+
+	// As with Go selectors, a column hides the columns of the same name
+	// promoted from deeper embedded structs.
+	shallowest := make(map[string]int, len(columns))
+	for _, column := range columns {
+		if d, ok := shallowest[column.name]; !ok || column.depth < d {
+			shallowest[column.name] = column.depth
+		}
+	}
+	visible := columns[:0]
+	seen := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if column.depth != shallowest[column.name] {
+			continue
+		}
+		if seen[column.name] {
+			return nil, fmt.Errorf("nilmapper: ToRows found two columns named %s in %s", column.name, t)
+		}
+		seen[column.name] = true
+		visible = append(visible, column)
+	}
+	return visible, nil
+}
+
+// columnType reports whether values of the struct type t are single columns.
+func columnType(t reflect.Type) bool {
+	for _, typ := range []reflect.Type{t, reflect.PtrTo(t)} {
+		if typ.Implements(valuerType) || typ.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the value of the column in elem, or nil if a pointer on the
+// way is nil.
+func (c rowColumn) value(elem reflect.Value) interface{} {
+	v := elem
+	for _, i := range c.path {
+		if v = derefValue(v); !v.IsValid() {
+			return nil
+		}
+		v = v.Field(i)
+	}
+	if v = derefValue(v); !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	return v.Interface()
+}
+
+// derefValue follows the pointers and interfaces of v, and returns the zero
+// Value if one of them is nil.
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
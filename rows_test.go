@@ -0,0 +1,118 @@
+package nilmapper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type ExportAudit struct {
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+type ExportAddress struct {
+	Street string `db:"street"`
+	City   *string
+}
+
+type ExportUser struct {
+	ExportAudit
+	ID       int            `db:"user_id" nilmapper:",order=1"`
+	Name     string         `db:"name" nilmapper:",order=2"`
+	Email    *string        `db:"email"`
+	Address  *ExportAddress `db:"address"`
+	Tags     []string       `db:"-"`
+	password string
+}
+
+func TestToRows(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	email, city := "ada@example.com", "London"
+	users := []*ExportUser{
+		{ExportAudit: ExportAudit{CreatedBy: "admin", CreatedAt: created}, ID: 1, Name: "Ada", Email: &email, Address: &ExportAddress{Street: "Main", City: &city}, Tags: []string{"a"}, password: "secret"},
+		{ID: 2, Name: "Grace"},
+		nil,
+	}
+
+	header, rows, err := ToRows(users)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, header, []string{"ID", "Name", "CreatedBy", "CreatedAt", "Email", "Address.Street", "Address.City", "Tags"})
+	assert.Equal(t, rows, [][]interface{}{
+		{1, "Ada", "admin", created, email, "Main", city, []string{"a"}},
+		{2, "Grace", "", time.Time{}, nil, nil, nil, nil},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+	})
+
+	header, _, err = ToRows(users, MatchTags([]string{"db"}, nil))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, header, []string{"user_id", "name", "CreatedBy", "CreatedAt", "email", "address.street", "address.City"})
+
+	_, _, err = ToRows(ExportUser{})
+	assert.Equal(t, err.Error(), "nilmapper: ToRows needs a slice of structs, got nilmapper.ExportUser")
+
+	type Tree struct {
+		Name   string
+		Parent *Tree
+	}
+	_, _, err = ToRows([]Tree{})
+	assert.Equal(t, err.Error(), "nilmapper: ToRows cannot flatten the recursive type nilmapper.Tree")
+}
+
+type ExportStamp struct {
+	ID int
+	By string
+}
+
+type ExportOwner struct {
+	ID int
+}
+
+type ExportAccount struct {
+	ExportStamp
+	ID   int
+	Name string
+	Hash string `nilmapper:"-"`
+}
+
+func TestToRowsShadowing(t *testing.T) {
+	users := []ExportAccount{{ExportStamp: ExportStamp{ID: 1, By: "admin"}, ID: 2, Name: "Ada", Hash: "secret"}}
+	header, rows, err := ToRows(users)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, header, []string{"By", "ID", "Name"})
+	assert.Equal(t, rows, [][]interface{}{{"admin", 2, "Ada"}})
+
+	type Deeper struct {
+		ExportAccount
+		By string
+	}
+	header, _, err = ToRows([]Deeper{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, header, []string{"ID", "Name", "By"})
+
+	type Both struct {
+		ExportStamp
+		ExportOwner
+	}
+	_, _, err = ToRows([]Both{})
+	assert.Equal(t, err.Error(), "nilmapper: ToRows found two columns named ID in nilmapper.Both")
+}
+
+func TestWriteCSV(t *testing.T) {
+	email := "ada@example.com"
+	users := []ExportUser{
+		{ID: 1, Name: "Ada, Countess", Email: &email, ExportAudit: ExportAudit{CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		{ID: 2, Name: "Grace"},
+	}
+	var buf bytes.Buffer
+	assert.Equal(t, WriteCSV(&buf, users, MatchTags([]string{"db"}, nil)), nil)
+	assert.Equal(t, buf.String(), strings.Join([]string{
+		"user_id,name,CreatedBy,CreatedAt,email,address.street,address.City",
+		`1,"Ada, Countess",,2024-01-02T00:00:00Z,ada@example.com,,`,
+		"2,Grace,,0001-01-01T00:00:00Z,,,",
+		"",
+	}, "\n"))
+}
@@ -0,0 +1,104 @@
+package nilmapper
+
+import (
+	"context"
+	"reflect"
+)
+
+// ToMapper is implemented by source types that map themselves. MapTo
+// receives a pointer to the destination and reports whether it handled the
+// mapping; if it did not, the destination is mapped by FromMapper or by
+// reflection as usual.
+//
+// MapTo is used for the value passed to Copy as well as for nested fields and
+// slice elements, so it must not call Copy with its own type again.
+type ToMapper interface {
+	MapTo(dst any) (handled bool, err error)
+}
+
+// FromMapper is implemented by destination types that map themselves from
+// the source value src. Like ToMapper, it is used for nested fields and slice
+// elements too.
+type FromMapper interface {
+	MapFrom(src any) error
+}
+
+// ToContextMapper is like ToMapper, but MapToContext also receives the
+// context given to CopyContext, CopySliceContext or the stream functions, or
+// context.Background() for Copy and CopySlice. A type implementing both
+// interfaces is only mapped by MapToContext.
+type ToContextMapper interface {
+	MapToContext(ctx context.Context, dst any) (handled bool, err error)
+}
+
+// FromContextMapper is like FromMapper, but MapFromContext also receives the
+// context of the call, as for ToContextMapper. It takes precedence over
+// MapFrom.
+type FromContextMapper interface {
+	MapFromContext(ctx context.Context, src any) error
+}
+
+var (
+	toMapperType          = typeOf[ToMapper]()
+	fromMapperType        = typeOf[FromMapper]()
+	toContextMapperType   = typeOf[ToContextMapper]()
+	fromContextMapperType = typeOf[FromContextMapper]()
+)
+
+// selfMappable reports whether values of srcType or destType take care of
+// their own mapping.
+func selfMappable(srcType reflect.Type, destType reflect.Type) bool {
+	srcType = indirectType(srcType)
+	destPtr := reflect.PtrTo(indirectType(destType))
+	return implements(srcType, toMapperType) || implements(srcType, toContextMapperType) ||
+		destPtr.Implements(fromMapperType) || destPtr.Implements(fromContextMapperType)
+}
+
+// mapSelf maps srcValue into the settable destValue with the MapTo method of
+// the source or the MapFrom method of the destination, or their context
+// variants, and reports whether one of them handled it.
+func (r *run) mapSelf(srcValue reflect.Value, destValue reflect.Value) (bool, error) {
+	if !selfMappable(srcValue.Type(), destValue.Type()) {
+		return false, nil
+	}
+	var mapTo func(dst any) (bool, error)
+	if src, ok := hookReceiver(srcValue, toContextMapperType).(ToContextMapper); ok {
+		mapTo = func(dst any) (bool, error) { return src.MapToContext(r.ctx, dst) }
+	} else if src, ok := hookReceiver(srcValue, toMapperType).(ToMapper); ok {
+		mapTo = src.MapTo
+	}
+	target := destValue
+	if destValue.Kind() == reflect.Ptr {
+		target = reflect.New(destValue.Type().Elem())
+	} else {
+		target = destValue.Addr()
+	}
+	var mapFrom func(src any) error
+	switch dest := target.Interface().(type) {
+	case FromContextMapper:
+		mapFrom = func(src any) error { return dest.MapFromContext(r.ctx, src) }
+	case FromMapper:
+		mapFrom = dest.MapFrom
+	}
+	if mapTo == nil && mapFrom == nil {
+		return false, nil
+	}
+
+	handled := false
+	if mapTo != nil {
+		var err error
+		if handled, err = mapTo(target.Interface()); err != nil {
+			return true, err
+		}
+	}
+	if !handled && mapFrom != nil && srcValue.CanInterface() {
+		if err := mapFrom(reflect.Indirect(srcValue).Interface()); err != nil {
+			return true, err
+		}
+		handled = true
+	}
+	if handled && destValue.Kind() == reflect.Ptr {
+		destValue.Set(target)
+	}
+	return handled, nil
+}
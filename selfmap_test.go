@@ -0,0 +1,161 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type Money struct {
+	Cents int64
+}
+
+func (m Money) MapTo(dst any) (bool, error) {
+	switch d := dst.(type) {
+	case *string:
+		*d = fmt.Sprintf("%d.%02d", m.Cents/100, m.Cents%100)
+		return true, nil
+	case *Money:
+		if m.Cents < 0 {
+			return true, errors.New("negative amount")
+		}
+	}
+	return false, nil
+}
+
+type Secret struct {
+	Plain string
+}
+
+func (s *Secret) MapFrom(src any) error {
+	switch v := src.(type) {
+	case string:
+		s.Plain = "decrypted:" + v
+	case Money:
+		s.Plain = fmt.Sprint(v.Cents)
+	default:
+		return fmt.Errorf("cannot decrypt %T", src)
+	}
+	return nil
+}
+
+type InvoiceSrc struct {
+	Amount  Money
+	Amounts []Money
+	Token   string
+	Backup  *Money
+}
+
+type InvoiceDst struct {
+	Amount  string
+	Amounts []Money
+	Token   *Secret
+	Backup  Secret
+}
+
+func TestSelfMapping(t *testing.T) {
+	assert.Equal(t, Configure[InvoiceSrc, InvoiceDst]().Field("Token", "Token").Err(), nil)
+
+	src := InvoiceSrc{
+		Amount:  Money{Cents: 1234},
+		Amounts: []Money{{Cents: 1}},
+		Token:   "abc",
+		Backup:  &Money{Cents: 7},
+	}
+	var dest InvoiceDst
+	assert.Equal(t, Copy(src, &dest), nil)
+	assert.Equal(t, dest.Amount, "12.34")
+	assert.Equal(t, dest.Amounts, []Money{{Cents: 1}})
+	assert.Equal(t, dest.Token.Plain, "decrypted:abc")
+	assert.Equal(t, dest.Backup.Plain, "7")
+
+	src.Amounts = []Money{{Cents: 1}, {Cents: -1}}
+	assert.Equal(t, Copy(src, &dest).Error(), "Amounts: index 1: negative amount")
+
+	var amount string
+	assert.Equal(t, Copy(Money{Cents: 5}, &amount), nil)
+	assert.Equal(t, amount, "0.05")
+
+	var secret Secret
+	assert.Equal(t, Copy(1, &secret).Error(), "cannot decrypt int")
+}
+
+// Cents maps itself with a pointer receiver.
+type Cents struct {
+	Value int64
+}
+
+func (c *Cents) MapTo(dst any) (bool, error) {
+	d, ok := dst.(*string)
+	if ok {
+		*d = fmt.Sprintf("%d cents", c.Value)
+	}
+	return ok, nil
+}
+
+func TestSelfMappingPointerReceiver(t *testing.T) {
+	type outer struct{ In Cents }
+	var dest struct{ In string }
+	// Neither the source passed by value nor its fields can be addressed.
+	assert.Equal(t, Copy(outer{In: Cents{Value: 5}}, &dest), nil)
+	assert.Equal(t, dest.In, "5 cents")
+
+	var amount string
+	assert.Equal(t, Copy(Cents{Value: 7}, &amount), nil)
+	assert.Equal(t, amount, "7 cents")
+}
+
+type localeKey struct{}
+
+// Price formats itself in the locale of the context.
+type Price struct {
+	Cents int64
+}
+
+func (p Price) MapTo(dst any) (bool, error) {
+	return false, errors.New("MapTo called instead of MapToContext")
+}
+
+func (p Price) MapToContext(ctx context.Context, dst any) (bool, error) {
+	d, ok := dst.(*string)
+	if ok {
+		sep := "."
+		if ctx.Value(localeKey{}) == "nl" {
+			sep = ","
+		}
+		*d = fmt.Sprintf("%d%s%02d", p.Cents/100, sep, p.Cents%100)
+	}
+	return ok, nil
+}
+
+// Label reads its value in the locale of the context.
+type Label struct {
+	Text string
+}
+
+func (l *Label) MapFromContext(ctx context.Context, src any) error {
+	l.Text = fmt.Sprintf("%v:%v", ctx.Value(localeKey{}), src)
+	return nil
+}
+
+func TestSelfMappingContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), localeKey{}, "nl")
+	src := struct {
+		Total Price
+		Title string
+	}{Total: Price{Cents: 1234}, Title: "order"}
+	var dest struct {
+		Total string
+		Title *Label
+	}
+	assert.Equal(t, CopyContext(ctx, src, &dest), nil)
+	assert.Equal(t, dest.Total, "12,34")
+	assert.Equal(t, dest.Title.Text, "nl:order")
+
+	var totals []string
+	assert.Equal(t, CopySlice([]Price{{Cents: 5}}, &totals), nil)
+	assert.Equal(t, totals, []string{"0.05"})
+}
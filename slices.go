@@ -0,0 +1,71 @@
+package nilmapper
+
+import "reflect"
+
+// SliceMode selects what CopySlice does with the slice it maps into.
+type SliceMode int
+
+const (
+	// ReplaceSlice replaces the destination with a new slice of mapped
+	// elements. It is the default.
+	ReplaceSlice SliceMode = iota
+	// AppendSlice appends the mapped elements to the destination, like the
+	// append builtin, reusing its backing array when the capacity allows.
+	AppendSlice
+	// ReuseSlice replaces the elements of the destination, but maps them
+	// into its backing array when the capacity allows, so that mapping into
+	// the same slice in a loop does not allocate a new one every time.
+	ReuseSlice
+	// MergeSlice maps every source element into the destination element at
+	// the same index instead of into a zero value, so that destination fields
+	// without a source field are kept. The destination gets the length of the
+	// source, and elements past the end of the destination start from zero
+	// values.
+	MergeSlice
+)
+
+// WithSliceMode sets the SliceMode used by CopySlice, and by Copy when it is
+// given slices. Slices nested in structs are always replaced. If mapping
+// fails, the backing array of the destination may have been partially
+// written by AppendSlice, ReuseSlice and MergeSlice.
+func WithSliceMode(mode SliceMode) Option {
+	return func(o *options) {
+		o.sliceMode = mode
+	}
+}
+
+// sliceTarget is the slice CopySlice maps into. Source element i is mapped
+// into element offset+i, starting from its current value when i < merged.
+type sliceTarget struct {
+	slice  reflect.Value
+	offset int
+	merged int
+}
+
+func (r *run) sliceTarget(destValue reflect.Value, srcLen int) sliceTarget {
+	switch r.sliceMode {
+	case AppendSlice:
+		n := destValue.Len()
+		if destValue.Cap() >= n+srcLen {
+			return sliceTarget{slice: destValue.Slice(0, n+srcLen), offset: n}
+		}
+		slice := reflect.MakeSlice(destValue.Type(), n+srcLen, n+srcLen+n/4)
+		reflect.Copy(slice, destValue)
+		return sliceTarget{slice: slice, offset: n}
+	case ReuseSlice, MergeSlice:
+		target := sliceTarget{}
+		if r.sliceMode == MergeSlice {
+			target.merged = destValue.Len()
+		}
+		if destValue.Cap() < srcLen {
+			target.slice = reflect.MakeSlice(destValue.Type(), srcLen, srcLen)
+			if target.merged > 0 {
+				reflect.Copy(target.slice, destValue)
+			}
+		} else {
+			target.slice = destValue.Slice(0, srcLen)
+		}
+		return target
+	}
+	return sliceTarget{slice: reflect.MakeSlice(destValue.Type(), srcLen, srcLen)}
+}
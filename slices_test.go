@@ -0,0 +1,72 @@
+package nilmapper
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestSliceModes(t *testing.T) {
+	src := []SignupSrc{{Name: "Ada", Email: "ada@example.com"}, {Name: "Bob", Email: "bob@example.com"}}
+
+	t.Run("Replace", func(t *testing.T) {
+		dest := []SignupDst{{Name: "Old"}}
+		assert.Equal(t, CopySlice(src, &dest), nil)
+		assert.Equal(t, dest, []SignupDst{
+			{Name: "Ada", Email: "ada@example.com", Country: "NL"},
+			{Name: "Bob", Email: "bob@example.com", Country: "NL"},
+		})
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		dest := []SignupDst{{Name: "Old"}}
+		assert.Equal(t, CopySlice(src, &dest, WithSliceMode(AppendSlice)), nil)
+		assert.Equal(t, len(dest), 3)
+		assert.Equal(t, dest[0], SignupDst{Name: "Old"})
+		assert.Equal(t, dest[2].Name, "Bob")
+
+		buf := make([]SignupDst, 1, 10)
+		assert.Equal(t, CopySlice(src, &buf, WithSliceMode(AppendSlice), Parallelism(2)), nil)
+		assert.Equal(t, len(buf), 3)
+		assert.Equal(t, cap(buf), 10)
+		assert.Equal(t, buf[1].Name, "Ada")
+	})
+
+	t.Run("Reuse", func(t *testing.T) {
+		buf := make([]SignupDst, 0, 4)
+		assert.Equal(t, CopySlice(src, &buf, WithSliceMode(ReuseSlice)), nil)
+		first := &buf[0]
+		assert.Equal(t, CopySlice(src[1:], &buf, WithSliceMode(ReuseSlice)), nil)
+		assert.Equal(t, len(buf), 1)
+		assert.Equal(t, &buf[0] == first, true)
+		assert.Equal(t, buf[0], SignupDst{Name: "Bob", Email: "bob@example.com", Country: "NL"})
+
+		allocs := testing.AllocsPerRun(10, func() {
+			_ = CopySlice(src, &buf, WithSliceMode(ReuseSlice))
+		})
+		replaced := testing.AllocsPerRun(10, func() {
+			_ = CopySlice(src, &buf)
+		})
+		if allocs >= replaced && !raceEnabled {
+			t.Errorf("expected fewer allocations when reusing the slice, got %v and %v", allocs, replaced)
+		}
+
+		small := make([]SignupDst, 0, 1)
+		assert.Equal(t, CopySlice(src, &small, WithSliceMode(ReuseSlice)), nil)
+		assert.Equal(t, len(small), 2)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		dest := []SignupDst{{Name: "Old", Country: "BE"}}
+		assert.Equal(t, CopySlice(src, &dest, WithSliceMode(MergeSlice)), nil)
+		assert.Equal(t, dest, []SignupDst{
+			{Name: "Ada", Email: "ada@example.com", Country: "BE"},
+			{Name: "Bob", Email: "bob@example.com", Country: "NL"},
+		})
+
+		dest = []SignupDst{{Country: "DE"}, {Country: "FR"}, {Country: "IT"}}
+		assert.Equal(t, CopySlice(src, &dest, WithSliceMode(MergeSlice)), nil)
+		assert.Equal(t, len(dest), 2)
+		assert.Equal(t, dest[1].Country, "FR")
+	})
+}
@@ -0,0 +1,115 @@
+package nilmapper
+
+import (
+	"context"
+	"fmt"
+)
+
+// MapChan maps every value received from in into a D, a struct type, with
+// the default Mapper, and sends it on the returned channel, so that streams
+// and cursors are mapped without holding more than one element. Both returned
+// channels are closed once in is closed. If an element cannot be mapped, or
+// ctx is done, the error is sent on the error channel and mapping stops. As
+// for CopySlice, a report given with WithReport covers the whole stream:
+//
+//	users, errc := nilmapper.MapChan[User, UserDTO](ctx, rows)
+//	for user := range users {
+//		...
+//	}
+//	if err := <-errc; err != nil {
+//		return err
+//	}
+func MapChan[S, D any](ctx context.Context, in <-chan S, opts ...Option) (<-chan D, <-chan error) {
+	return MapChanOn[S, D](ctx, defaultMapper, in, opts...)
+}
+
+// MapChanOn is like MapChan, but uses the configurations, hooks and options
+// of m.
+func MapChanOn[S, D any](ctx context.Context, m *Mapper, in <-chan S, opts ...Option) (<-chan D, <-chan error) {
+	out := make(chan D)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(out)
+		r := m.newRun(ctx, opts)
+		defer r.release()
+		for i := 0; ; i++ {
+			var src S
+			var ok bool
+			select {
+			case src, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				errc <- fmt.Errorf("nilmapper: index %d: %w", i, ctx.Err())
+				return
+			}
+			dest, err := mapElem[S, D](r, i, src)
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case out <- dest:
+			case <-ctx.Done():
+				errc <- fmt.Errorf("nilmapper: index %d: %w", i, ctx.Err())
+				return
+			}
+		}
+	}()
+	return out, errc
+}
+
+// MapSeq maps the values yielded by seq into values of D, a struct type,
+// with the default Mapper. The returned function has the shape of an
+// iter.Seq2[D, error]: it yields every mapped value with a nil error, or the
+// zero D with the error of the first element which cannot be mapped, or with
+// the error of ctx once it is done, and then stops. ctx is also passed to
+// context-aware resolvers.
+func MapSeq[S, D any](ctx context.Context, seq func(yield func(S) bool), opts ...Option) func(yield func(D, error) bool) {
+	return MapSeqOn[S, D](ctx, defaultMapper, seq, opts...)
+}
+
+// MapSeqOn is like MapSeq, but uses the configurations, hooks and options of
+// m.
+func MapSeqOn[S, D any](ctx context.Context, m *Mapper, seq func(yield func(S) bool), opts ...Option) func(yield func(D, error) bool) {
+	return func(yield func(D, error) bool) {
+		r := m.newRun(ctx, opts)
+		defer r.release()
+		i := 0
+		seq(func(src S) bool {
+			var dest D
+			err := ctx.Err()
+			if err != nil {
+				err = fmt.Errorf("nilmapper: index %d: %w", i, err)
+			} else {
+				dest, err = mapElem[S, D](r, i, src)
+			}
+			i++
+			if err != nil {
+				var zero D
+				yield(zero, err)
+				return false
+			}
+			return yield(dest, nil)
+		})
+	}
+}
+
+// mapElem maps the element at index i of a stream with r, the run of the
+// whole stream.
+func mapElem[S, D any](r *run, i int, src S) (D, error) {
+	var dest D
+	r.enter(fmt.Sprintf("[%d]", i))
+	err := r.mapStruct(src, &dest)
+	r.leave()
+	if err == nil {
+		err = r.finish()
+	}
+	if err != nil {
+		var zero D
+		return zero, fmt.Errorf("nilmapper: index %d: %w", i, err)
+	}
+	return dest, nil
+}
@@ -0,0 +1,122 @@
+package nilmapper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func rowChan(n int) <-chan Row {
+	in := make(chan Row)
+	go func() {
+		defer close(in)
+		for i := 0; i < n; i++ {
+			in <- Row{ID: i}
+		}
+	}()
+	return in
+}
+
+func TestMapChan(t *testing.T) {
+	t.Run("All", func(t *testing.T) {
+		out, errc := MapChan[Row, RowDTO](context.Background(), rowChan(100))
+		var ids []int
+		for dest := range out {
+			ids = append(ids, dest.ID)
+		}
+		assert.Equal(t, <-errc, nil)
+		assert.Equal(t, len(ids), 100)
+		assert.Equal(t, ids[99], 99)
+
+		out, errc = MapChanOn[Row, RowDTO](context.Background(), New(), rowChan(3))
+		ids = nil
+		for dest := range out {
+			ids = append(ids, dest.ID)
+		}
+		assert.Equal(t, <-errc, nil)
+		assert.Equal(t, ids, []int{0, 1, 2})
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		in := make(chan SignupSrc, 2)
+		in <- SignupSrc{Email: "a@example.com"}
+		in <- SignupSrc{Name: "Ada"}
+		close(in)
+		out, errc := MapChan[SignupSrc, SignupDst](context.Background(), in)
+		var count int
+		for range out {
+			count++
+		}
+		assert.Equal(t, count, 1)
+		assert.Equal(t, (<-errc).Error(), "nilmapper: index 1: email is required")
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		out, errc := MapChan[Row, RowDTO](ctx, make(chan Row))
+		cancel()
+		for range out {
+			t.Fatal("unexpected value")
+		}
+		err := <-errc
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	})
+}
+
+func TestMapSeq(t *testing.T) {
+	rows := func(yield func(Row) bool) {
+		for i := 0; i < 5; i++ {
+			if !yield(Row{ID: i}) {
+				return
+			}
+		}
+	}
+
+	var ids []int
+	MapSeq[Row, RowDTO](context.Background(), rows)(func(dest RowDTO, err error) bool {
+		assert.Equal(t, err, nil)
+		ids = append(ids, dest.ID)
+		return dest.ID < 2
+	})
+	assert.Equal(t, ids, []int{0, 1, 2})
+
+	signups := func(yield func(SignupSrc) bool) {
+		_ = yield(SignupSrc{Email: "a@example.com"}) && yield(SignupSrc{}) && yield(SignupSrc{Email: "c@example.com"})
+	}
+	var errs []error
+	MapSeq[SignupSrc, SignupDst](context.Background(), signups)(func(dest SignupDst, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+	assert.Equal(t, len(errs), 2)
+	assert.Equal(t, errs[0], nil)
+	assert.Equal(t, errs[1].Error(), "nilmapper: index 1: email is required")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ids = nil
+	MapSeqOn[Row, RowDTO](ctx, New(), rows)(func(dest RowDTO, err error) bool {
+		if err != nil {
+			assert.Equal(t, errors.Is(err, context.Canceled), true)
+			assert.Equal(t, err.Error(), "nilmapper: index 2: context canceled")
+			return false
+		}
+		ids = append(ids, dest.ID)
+		if dest.ID == 1 {
+			cancel()
+		}
+		return true
+	})
+	assert.Equal(t, ids, []int{0, 1})
+
+	// The elements are mapped by a single run, which reports all of them.
+	var report Report
+	MapSeq[Row, RowDTO](context.Background(), rows, WithReport(&report))(func(dest RowDTO, err error) bool {
+		return dest.ID < 1
+	})
+	assert.Equal(t, report.Written, []string{"[0].ID", "[1].ID"})
+	assert.Equal(t, report.Unmatched, []string{"[0].Tenant", "[1].Tenant"})
+}
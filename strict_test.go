@@ -0,0 +1,96 @@
+package nilmapper
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type StrictAddress struct {
+	Street string
+	City   string
+}
+
+type StrictSrc struct {
+	Name     string
+	Renamed  string
+	Comment  string `nilmapper:",optional"`
+	Address  StrictAddress
+	Lines    []StrictAddress
+	Tags     []string
+	internal string
+}
+
+type StrictAddressDst struct {
+	Street  string
+	Country string
+}
+
+type StrictDst struct {
+	Name    string
+	NewName string
+	Note    string `nilmapper:",optional"`
+	Address StrictAddressDst
+	Lines   []StrictAddress
+	Tags    []string
+	Ignored string
+	secret  string
+}
+
+func TestStrict(t *testing.T) {
+	src := StrictSrc{
+		Name:    "Name",
+		Address: StrictAddress{Street: "Street"},
+		Lines:   []StrictAddress{{City: "A"}, {City: "B"}},
+		Tags:    []string{"tag"},
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		var dest StrictDst
+		assert.Equal(t, Copy(src, &dest), nil)
+	})
+
+	t.Run("Destination", func(t *testing.T) {
+		var dest StrictDst
+		err := Copy(src, &dest, RequireAllDestinationFields())
+		var unmatched *UnmatchedFieldsError
+		if !errors.As(err, &unmatched) {
+			t.Fatalf("expected an UnmatchedFieldsError, got %v", err)
+		}
+		assert.Equal(t, unmatched.Destination, []string{"Address.Country", "NewName", "Ignored"})
+		assert.Equal(t, len(unmatched.Source), 0)
+		assert.Equal(t, dest.Name, "Name")
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		var dest []StrictDst
+		err := CopySlice([]StrictSrc{src, src}, &dest, RequireAllSourceFields())
+		assert.Equal(t, err.Error(), "nilmapper: unmatched source fields: Renamed, Address.City")
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		m := New(RequireAllDestinationFields(), RequireAllSourceFields())
+		cfg := ConfigureOn[StrictSrc, StrictDst](m).
+			Field("NewName", "Renamed").
+			Ignore("Ignored").
+			Ignore("Address.Country").
+			Ignore("Tags")
+		assert.Equal(t, cfg.Err(), nil)
+
+		var dest StrictDst
+		err := m.Copy(src, &dest)
+		assert.Equal(t, err.Error(), "nilmapper: unmatched source fields: Address.City")
+	})
+
+	t.Run("No conversion", func(t *testing.T) {
+		var dest struct{ Count string }
+		err := Copy(struct{ Count int }{Count: 3}, &dest, RequireAllDestinationFields(), RequireAllSourceFields())
+		var unmatched *UnmatchedFieldsError
+		if !errors.As(err, &unmatched) {
+			t.Fatalf("expected an UnmatchedFieldsError, got %v", err)
+		}
+		assert.Equal(t, unmatched.Destination, []string{"Count"})
+		assert.Equal(t, unmatched.Source, []string{"Count"})
+	})
+}
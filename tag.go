@@ -0,0 +1,70 @@
+package nilmapper
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tagName is the key of the struct tag read by nilmapper. Its value is a
+// comma separated list of options, the first element being reserved for a
+// field name:
+//
+//	Comment string `nilmapper:",optional"`
+//	Items   []Item `nilmapper:",mergekey=ID,prune"`
+//	Email   string `nilmapper:",order=2"`
+//	Hash    string `nilmapper:"-"`
+const tagName = "nilmapper"
+
+type fieldTag struct {
+	optional bool
+	mergeKey string
+	prune    bool
+	// order is the position of the column of the field in ToRows, from 1.
+	order int
+	// omit leaves the field out of ToRows.
+	omit bool
+}
+
+func parseTag(field reflect.StructField) fieldTag {
+	var tag fieldTag
+	value, ok := field.Tag.Lookup(tagName)
+	if !ok {
+		return tag
+	}
+	opts := strings.Split(value, ",")
+	tag.omit = value == "-"
+	for _, opt := range opts[1:] {
+		opt = strings.TrimSpace(opt)
+		switch {
+		case opt == "optional":
+			tag.optional = true
+		case opt == "prune":
+			tag.prune = true
+		case strings.HasPrefix(opt, "mergekey="):
+			tag.mergeKey = strings.TrimPrefix(opt, "mergekey=")
+		case strings.HasPrefix(opt, "order="):
+			tag.order, _ = strconv.Atoi(strings.TrimPrefix(opt, "order="))
+		}
+	}
+	return tag
+}
+
+// fieldName returns the name given to field by the first struct tag of
+// tagKeys it has, ignoring tag options, or its Go name. A tag of "-" ignores
+// the field.
+func fieldName(field reflect.StructField, tagKeys []string) (name string, ignored bool) {
+	for _, key := range tagKeys {
+		value, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		if value == "-" {
+			return "", true
+		}
+		if name, _, _ := strings.Cut(value, ","); name != "" {
+			return name, false
+		}
+	}
+	return field.Name, false
+}
@@ -0,0 +1,124 @@
+package nilmapper
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type cacheEntry struct {
+	Key   string
+	Value int
+}
+
+type CacheSrc struct {
+	Name    string
+	entries []cacheEntry
+	hits    *int
+	index   map[string]int
+}
+
+type CacheDst struct {
+	Name    string
+	Entries []cacheEntry
+	entries []cacheEntry
+	hits    *int
+	index   map[string]int
+}
+
+func TestUnexportedFields(t *testing.T) {
+	hits := 3
+	src := CacheSrc{
+		Name:    "cache",
+		entries: []cacheEntry{{Key: "a", Value: 1}},
+		hits:    &hits,
+		index:   map[string]int{"a": 0},
+	}
+
+	t.Run("Skipped", func(t *testing.T) {
+		var dest CacheDst
+		assert.Equal(t, Copy(src, &dest), nil)
+		assert.Equal(t, dest, CacheDst{Name: "cache"})
+
+		// entries only matches Entries, which used to panic.
+		var exported ExportedSrc
+		assert.Equal(t, Copy(src, &exported), nil)
+		assert.Equal(t, exported.Entries, []cacheEntry(nil))
+	})
+
+	t.Run("Copied", func(t *testing.T) {
+		var dest CacheDst
+		assert.Equal(t, Copy(src, &dest, CopyUnexported()), nil)
+		assert.Equal(t, dest.Name, "cache")
+		assert.Equal(t, dest.Entries, []cacheEntry(nil))
+		assert.Equal(t, dest.entries, []cacheEntry{{Key: "a", Value: 1}})
+		assert.Equal(t, *dest.hits, 3)
+		assert.Equal(t, dest.index, map[string]int{"a": 0})
+
+		dest.entries[0].Value = 2
+		assert.Equal(t, src.entries[0].Value, 1)
+
+		dest.index["b"] = 1
+		assert.Equal(t, len(src.index), 1)
+	})
+
+	t.Run("Containers", func(t *testing.T) {
+		type pool struct {
+			byKey map[string]*cacheEntry
+			slots [2]*cacheEntry
+		}
+		src := pool{
+			byKey: map[string]*cacheEntry{"a": {Key: "a"}, "none": nil},
+			slots: [2]*cacheEntry{{Key: "b"}},
+		}
+		var dest pool
+		assert.Equal(t, Copy(src, &dest, CopyUnexported()), nil)
+		assert.Equal(t, dest, src)
+		assert.Equal(t, dest.byKey["a"] == src.byKey["a"], false)
+		assert.Equal(t, dest.slots[0] == src.slots[0], false)
+		assert.Equal(t, dest.byKey["none"], (*cacheEntry)(nil))
+		assert.Equal(t, dest.slots[1], (*cacheEntry)(nil))
+	})
+
+	t.Run("Slice", func(t *testing.T) {
+		var dest []CacheDst
+		assert.Equal(t, CopySlice([]CacheSrc{src}, &dest, CopyUnexported()), nil)
+		assert.Equal(t, dest[0].entries, src.entries)
+	})
+
+	t.Run("Plan", func(t *testing.T) {
+		assert.Equal(t, ValidatePair(typeOf[CacheSrc](), typeOf[CacheDst](), AllowUnmappedDestination()), nil)
+		assert.Equal(t, ValidatePair(typeOf[CacheSrc](), typeOf[CacheDst](), CopyUnexported(), AllowUnmappedDestination()), nil)
+	})
+}
+
+type ExportedSrc struct {
+	Entries []cacheEntry
+}
+
+func TestUnexportedDestination(t *testing.T) {
+	var dest struct{ entries []cacheEntry }
+	src := ExportedSrc{Entries: []cacheEntry{{Key: "a"}}}
+	assert.Equal(t, Copy(src, &dest), nil)
+	assert.Equal(t, dest.entries, []cacheEntry(nil))
+
+	assert.Equal(t, Copy(src, &dest, CopyUnexported()), nil)
+	assert.Equal(t, dest.entries, src.Entries)
+}
+
+type shadowedDst struct {
+	ID int
+	id int
+}
+
+func TestUnexportedNotAmbiguous(t *testing.T) {
+	// id is not copied, so Id only matches ID.
+	src := struct{ Id int }{Id: 7}
+	var dest shadowedDst
+	assert.Equal(t, Copy(src, &dest), nil)
+	assert.Equal(t, dest, shadowedDst{ID: 7})
+	assert.Equal(t, ValidatePair(typeOf[struct{ Id int }](), typeOf[shadowedDst]()), nil)
+
+	_, isAmbiguous := Copy(src, &dest, CopyUnexported()).(*AmbiguousFieldError)
+	assert.Equal(t, isAmbiguous, true)
+}
@@ -1,45 +1,66 @@
 package nilmapper
 
-import "reflect"
+import (
+	"reflect"
+	"unsafe"
+)
 
 func ToValue[T any](s T) *T {
 	return &s
 }
-func getComplex(srcFieldValue reflect.Value) complex128 {
-	var f complex128
-	if srcFieldValue.Kind() == reflect.Ptr {
-		f = srcFieldValue.Elem().Complex()
-	} else {
-		f = srcFieldValue.Complex()
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
 	}
-	return f
+	return t
 }
 
-func getFloat(srcFieldValue reflect.Value) float64 {
-	var f float64
-	if srcFieldValue.Kind() == reflect.Ptr {
-		f = srcFieldValue.Elem().Float()
-	} else {
-		f = srcFieldValue.Float()
+// fieldByIndexAlloc is like reflect.Value.FieldByIndex, but allocates nil
+// embedded struct pointers on the way instead of panicking.
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
 	}
-	return f
+	return v
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
 }
-func getUint(srcFieldValue reflect.Value) uint64 {
-	var f uint64
-	if srcFieldValue.Kind() == reflect.Ptr {
-		f = srcFieldValue.Elem().Uint()
-	} else {
-		f = srcFieldValue.Uint()
+
+// addressable returns v if it is addressable and an addressable copy of it
+// otherwise.
+func addressable(v reflect.Value) reflect.Value {
+	if v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// exposed returns v without the read-only flag reflect sets on values
+// reached through unexported fields, so it can be read and set. v must be
+// addressable.
+func exposed(v reflect.Value) reflect.Value {
+	if v.CanInterface() {
+		return v
 	}
-	return f
+	return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem()
 }
 
-func getIntFromSrcValue(srcFieldValue reflect.Value) int64 {
-	var f int64
-	if srcFieldValue.Kind() == reflect.Ptr {
-		f = srcFieldValue.Elem().Int()
-	} else {
-		f = srcFieldValue.Int()
+// valueAs returns v as a T, taking its address if T is a pointer to the type
+// of v.
+func valueAs[T any](v reflect.Value) T {
+	if t := typeOf[T](); t.Kind() == reflect.Ptr && v.Type() == t.Elem() {
+		v = addressable(v).Addr()
 	}
-	return f
+	return v.Interface().(T)
 }
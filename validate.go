@@ -0,0 +1,176 @@
+package nilmapper
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// IssueKind classifies the problems reported by Validate.
+type IssueKind int
+
+const (
+	// UnmatchedSource is a source field without a destination field. It is
+	// only reported with RequireAllSourceFields.
+	UnmatchedSource IssueKind = iota + 1
+	// UnmatchedDestination is a destination field without a source field. It
+	// is not reported for optional fields, or with AllowUnmappedDestination.
+	UnmatchedDestination
+	// Skipped is a field matched by name which cannot be set, because the
+	// destination field is not exported.
+	Skipped
+	// NoConversion is a field whose source type cannot be mapped into the
+	// destination type, so the field is skipped.
+	NoConversion
+	// Ambiguous is a source field matching several destination fields
+	// through the NameMatcher, so Copy fails.
+	Ambiguous
+	// InvalidConfig is a configuration registered with Configure which has
+	// errors.
+	InvalidConfig
+	// Irreversible is a source field of a configuration built by
+	// Config.Reverse which cannot be mapped back, because the resolver
+	// producing it has no Inverse and is not marked OneWay.
+	Irreversible
+	// Fails is a field whose mapping returns an error, because the source
+	// value cannot be assigned to the destination field.
+	Fails
+	// Shadowed is a source field matching a destination field which
+	// another source field matches more closely, for example by its struct
+	// tag, so it is not mapped.
+	Shadowed
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case UnmatchedSource:
+		return "unmatched source"
+	case UnmatchedDestination:
+		return "unmatched destination"
+	case Skipped:
+		return "skipped"
+	case NoConversion:
+		return "no conversion"
+	case Ambiguous:
+		return "ambiguous"
+	case InvalidConfig:
+		return "invalid config"
+	case Fails:
+		return "fails"
+	case Irreversible:
+		return "irreversible"
+	case Shadowed:
+		return "shadowed"
+	}
+	return fmt.Sprintf("IssueKind(%d)", int(k))
+}
+
+// MarshalText encodes the kind as its name.
+func (k IssueKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Issue is a single problem found by Validate. Path is the path of the
+// destination field, or of the source field for UnmatchedSource issues.
+type Issue struct {
+	Kind    IssueKind `json:"kind"`
+	Path    string    `json:"path,omitempty"`
+	Message string    `json:"message"`
+}
+
+func (i Issue) String() string {
+	if i.Path == "" {
+		return fmt.Sprintf("%s: %s", i.Kind, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Path, i.Kind, i.Message)
+}
+
+// ValidationError lists the issues found by Validate for a type pair.
+type ValidationError struct {
+	Src    reflect.Type
+	Dst    reflect.Type
+	Issues []Issue
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "nilmapper: mapping %s to %s:", e.Src, e.Dst)
+	for _, issue := range e.Issues {
+		b.WriteString("\n\t")
+		b.WriteString(issue.String())
+	}
+	return b.String()
+}
+
+// Validate checks the mapping of Src into Dst with the default Mapper
+// without needing any values, and returns a *ValidationError listing every
+// field which Copy would skip silently, fail on or panic on. Validate is meant
+// to be called from init functions or tests:
+//
+//	func TestMappings(t *testing.T) {
+//		if err := nilmapper.Validate[User, UserDTO](); err != nil {
+//			t.Error(err)
+//		}
+//	}
+//
+// Destination fields without a source are reported unless they are tagged
+// with `nilmapper:",optional"` or AllowUnmappedDestination is given. Source
+// fields without a destination are only reported with RequireAllSourceFields.
+func Validate[Src, Dst any](opts ...Option) error {
+	return defaultMapper.ValidatePair(typeOf[Src](), typeOf[Dst](), opts...)
+}
+
+// ValidatePair is the non-generic form of Validate.
+func ValidatePair(srcType reflect.Type, destType reflect.Type, opts ...Option) error {
+	return defaultMapper.ValidatePair(srcType, destType, opts...)
+}
+
+// ValidatePair is like the package level ValidatePair, but uses the
+// configurations and options of m.
+func (m *Mapper) ValidatePair(srcType reflect.Type, destType reflect.Type, opts ...Option) error {
+	o := m.opts.with(opts)
+	plan := m.plan(srcType, destType, o)
+	var issues []Issue
+	if plan.Conversion == ConvNone {
+		issues = append(issues, Issue{Kind: NoConversion, Message: fmt.Sprintf("cannot map %s into %s", srcType, destType)})
+	}
+	issues = collectIssues(issues, plan.Fields, "", o)
+	if len(issues) == 0 {
+		return nil
+	}
+	return &ValidationError{Src: srcType, Dst: destType, Issues: issues}
+}
+
+// collectIssues appends the issues of fields and their nested fields to
+// issues, adding the unmatched fields required by o.
+func collectIssues(issues []Issue, fields []FieldPlan, path string, o options) []Issue {
+	for _, field := range fields {
+		fieldPath := field.Dest
+		if fieldPath == "" {
+			fieldPath = field.Source
+		}
+		fieldPath = joinPath(path, fieldPath)
+		switch {
+		case field.Match == MatchUnmapped && (o.requireDestination || !o.allowUnmapped) && !field.Optional && len(field.Issues) == 0:
+			issues = append(issues, Issue{Kind: UnmatchedDestination, Path: fieldPath, Message: "no source field"})
+		case field.Match == MatchUnmatched && o.requireSource && !field.Optional:
+			issues = append(issues, Issue{Kind: UnmatchedSource, Path: fieldPath, Message: "no destination field"})
+		}
+		for _, issue := range field.Issues {
+			issue.Path = joinPath(fieldPath, issue.Path)
+			issues = append(issues, issue)
+		}
+		issues = collectIssues(issues, field.Fields, fieldPath, o)
+	}
+	return issues
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	if name == "" {
+		return path
+	}
+	return path + "." + name
+}
@@ -0,0 +1,119 @@
+package nilmapper
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+type Status string
+
+type ValidSrc struct {
+	ID        int
+	Name      string
+	Addresses []Address
+	Extra     string
+}
+
+type ValidDst struct {
+	Id        int
+	Name      *string
+	Addresses []Address2
+	Note      string `nilmapper:",optional"`
+}
+
+type BrokenSrc struct {
+	Count    int
+	Status   Status
+	Lookup   map[string]int
+	Reader   string
+	UserId   string
+	Nested   BrokenNested
+	Exported string
+}
+
+type BrokenNested struct {
+	Values []int
+}
+
+type BrokenNestedDst struct {
+	Values []string
+}
+
+type BrokenDst struct {
+	Count    string
+	Status   *Status
+	Lookup   *map[string]int
+	Reader   interface{ Read([]byte) (int, error) }
+	UserID   string
+	USERID   string
+	Nested   BrokenNestedDst
+	exported string
+}
+
+func TestValidate(t *testing.T) {
+	assert.Equal(t, Validate[ValidSrc, ValidDst](), nil)
+	assert.Equal(t, Validate[Src3, Dst3](), nil)
+
+	err := Validate[ValidSrc, ValidDst](RequireAllSourceFields(), RequireAllDestinationFields())
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	assert.Equal(t, validation.Issues, []Issue{
+		{Kind: UnmatchedSource, Path: "Extra", Message: "no destination field"},
+	})
+}
+
+func TestValidateUnmapped(t *testing.T) {
+	type dest struct {
+		Name    string
+		Comment string
+		Note    string `nilmapper:",optional"`
+	}
+	err := ValidatePair(reflect.TypeOf(struct{ Name string }{}), reflect.TypeOf(dest{}))
+	assert.Equal(t, err.(*ValidationError).Issues, []Issue{
+		{Kind: UnmatchedDestination, Path: "Comment", Message: "no source field"},
+	})
+	assert.Equal(t, ValidatePair(reflect.TypeOf(struct{ Name string }{}), reflect.TypeOf(dest{}), AllowUnmappedDestination()), nil)
+}
+
+func TestValidatePair(t *testing.T) {
+	err := ValidatePair(reflect.TypeOf(BrokenSrc{}), reflect.TypeOf(BrokenDst{}))
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	var kinds []string
+	for _, issue := range validation.Issues {
+		kinds = append(kinds, issue.Path+" "+issue.Kind.String())
+	}
+	assert.Equal(t, kinds, []string{
+		"Count no conversion",
+		"Reader fails",
+		"Nested.Values no conversion",
+		"exported skipped",
+		"UserId ambiguous",
+	})
+}
+
+func TestValidateConfig(t *testing.T) {
+	m := New()
+	ConfigureOn[BrokenSrc, BrokenDst](m).
+		Ignore("Count").
+		Ignore("Status").
+		Ignore("Lookup").
+		Ignore("Reader").
+		Field("UserID", "UserId").
+		Ignore("Nested")
+	assert.Equal(t, m.ValidatePair(reflect.TypeOf(BrokenSrc{}), reflect.TypeOf(BrokenDst{})).Error(),
+		"nilmapper: mapping nilmapper.BrokenSrc to nilmapper.BrokenDst:\n"+
+			"\tUSERID: unmatched destination: no source field\n"+
+			"\texported: skipped: destination field is not exported")
+
+	ConfigureOn[BrokenSrc, BrokenDst](m).Field("Count", "Count")
+	err := m.ValidatePair(reflect.TypeOf(BrokenSrc{}), reflect.TypeOf(BrokenDst{}))
+	assert.Equal(t, err.(*ValidationError).Issues[0].Kind, InvalidConfig)
+}